@@ -1,89 +1,141 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/samber/lo"
-	"github.com/vpineda1996/wealthgo/client/graphql/generated"
+	"github.com/vpineda1996/wealthgo/client/graphql/queries"
 )
 
-// GetAccounts retrieves accounts
-func (api *WealthsimpleAPI) GetAccounts(openOnly bool, useCache bool) ([]generated.Account, error) {
+// accountsPageSize is the number of accounts requested per page
+const accountsPageSize = 25
+
+// GetAccounts retrieves accounts, following pagination until every account is fetched
+func (api *WealthsimpleAPI) GetAccounts(openOnly bool, useCache bool) ([]queries.AccountWithFinancials, error) {
+	return api.GetAccountsWithContext(context.Background(), openOnly, useCache)
+}
+
+// GetAccountsWithContext retrieves accounts, requests are bound to ctx
+func (api *WealthsimpleAPI) GetAccountsWithContext(ctx context.Context, openOnly bool, useCache bool) ([]queries.AccountWithFinancials, error) {
 	cacheKey := "all"
 	if openOnly {
 		cacheKey = "open"
 	}
 
-	if !useCache || api.AccountCache[cacheKey] == nil {
-		tokenInfo, err := api.GetTokenInfo()
-		if err != nil {
-			return nil, err
+	if useCache {
+		api.accountCacheMu.RLock()
+		accounts := api.AccountCache[cacheKey]
+		api.accountCacheMu.RUnlock()
+		if accounts != nil {
+			return accounts, nil
 		}
+	}
 
-		identityID := tokenInfo.IdentityCanonicalId
-		accounts, err := DoGraphQLQuery[[]generated.Account](
-			&api.WealthsimpleAPIBase,
-			GraphQlQueryOpts{
-				QueryName: "FetchAllAccountFinancials",
-				Variables: map[string]any{
-					"pageSize":   25,
-					"identityId": identityID,
-				},
-				DataResponsePath: "identity.accounts.edges",
-				ExpectType:       arrayType,
+	tokenInfo, err := api.GetTokenInfoWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	identityID := tokenInfo.IdentityCanonicalId
+	accounts, err := collectPages(PaginateGraphQLOperation(
+		ctx,
+		&api.WealthsimpleAPIBase,
+		OperationPaginateOpts[queries.FetchAllAccountFinancialsResponse, queries.AccountWithFinancials]{
+			QueryName: "FetchAllAccountFinancials",
+			Variables: map[string]any{
+				"pageSize":   accountsPageSize,
+				"identityId": identityID,
 			},
-		)
-		if err != nil {
-			return nil, err
-		}
+			Connection: func(data *queries.FetchAllAccountFinancialsResponse) ([]queries.AccountWithFinancials, *PageInfo) {
+				if data.Identity == nil || data.Identity.Accounts == nil {
+					return nil, nil
+				}
+				connection := data.Identity.Accounts
+				nodes := lo.Map(connection.Edges, func(edge queries.AllAccountFinancialsAccountsEdges, _ int) queries.AccountWithFinancials {
+					return edge.Node
+				})
+				return nodes, &PageInfo{HasNextPage: connection.PageInfo.HasNextPage, EndCursor: lo.FromPtr(connection.PageInfo.EndCursor)}
+			},
+		},
+	))
+	if accounts == nil && err != nil {
+		return nil, err
+	}
 
-		accounts = lo.Filter(accounts, func(acc generated.Account, _ int) bool {
-			if openOnly {
-				return acc.Status == "open"
-			} else {
-				return true
-			}
-		})
+	accounts = lo.Filter(accounts, func(acc queries.AccountWithFinancials, _ int) bool {
+		if openOnly {
+			return acc.Status == "open"
+		} else {
+			return true
+		}
+	})
 
-		api.AccountCache[cacheKey] = accounts
+	// Partial results are returned along with the GraphQL errors but aren't cached
+	if err != nil {
+		return accounts, err
 	}
 
-	return api.AccountCache[cacheKey], nil
+	api.accountCacheMu.Lock()
+	api.AccountCache[cacheKey] = accounts
+	api.accountCacheMu.Unlock()
+	return accounts, nil
 }
 
 // GetAccountBalances retrieves account balances
 func (api *WealthsimpleAPI) GetAccountBalances(accountID string) (map[SecuritySymbol]string, error) {
+	return api.GetAccountBalancesWithContext(context.Background(), accountID)
+}
+
+// GetAccountBalancesWithContext retrieves account balances, requests are bound to ctx
+func (api *WealthsimpleAPI) GetAccountBalancesWithContext(ctx context.Context, accountID string) (map[SecuritySymbol]string, error) {
 
-	accounts, err := DoGraphQLQuery[[]generated.Account](
+	data, err := DoGraphQLOperation[queries.FetchAccountsWithBalanceResponse](
+		ctx,
 		&api.WealthsimpleAPIBase,
-		GraphQlQueryOpts{
-			QueryName: "FetchAccountsWithBalance",
-			Variables: map[string]interface{}{
-				"type": "TRADING",
-				"ids":  []string{accountID},
-			},
-			DataResponsePath: "accounts",
-			ExpectType:       arrayType,
+		"FetchAccountsWithBalance",
+		map[string]interface{}{
+			"type": "TRADING",
+			"ids":  []string{accountID},
 		})
-	if err != nil {
+	if data == nil {
 		return nil, err
 	}
 
+	accounts := data.Accounts
 	if len(accounts) != 1 {
-		return nil, fmt.Errorf("%w: no account found, got %d", ErrUnexpected, len(accounts))
+		if err == nil {
+			err = fmt.Errorf("%w: no account found, got %d", ErrUnexpected, len(accounts))
+		}
+		return nil, err
 	}
 
-	balances := make(map[SecuritySymbol]string)
 	custodianAccounts := accounts[0].CustodianAccounts
+
+	// Resolve every symbol in a single batched round trip, the lookups below are then served by the cache
+	if api.SecurityMarketDataCacheGetter != nil {
+		var securityIDs []string
+		for _, ca := range custodianAccounts {
+			for _, b := range custodianBalances(ca) {
+				if b.SecurityId != "sec-c-cad" && b.SecurityId != "sec-c-usd" {
+					securityIDs = append(securityIDs, b.SecurityId)
+				}
+			}
+		}
+		if _, err := api.GetSecuritiesMarketDataWithContext(ctx, securityIDs, true); err != nil {
+			api.logger().DebugContext(ctx, "batched market data lookup failed", slog.String("error", err.Error()))
+		}
+	}
+
+	balances := make(map[SecuritySymbol]string)
 	for _, ca := range custodianAccounts {
-		financials := ca.Financials
-		balance := financials.Balance
-		for _, b := range balance {
+		for _, b := range custodianBalances(ca) {
 			securityId := b.SecurityId
 			quantity := b.Quantity
 
 			if securityId != "sec-c-cad" && securityId != "sec-c-usd" {
-				symbol, err := api.SecurityIDToSymbol(securityId)
+				symbol, err := api.SecurityIDToSymbolWithContext(ctx, securityId)
 				if err != nil {
 					continue
 				}
@@ -93,5 +145,14 @@ func (api *WealthsimpleAPI) GetAccountBalances(accountID string) (map[SecuritySy
 		}
 	}
 
-	return balances, nil
+	// Partial results are returned along with the GraphQL errors, if any
+	return balances, err
+}
+
+// custodianBalances returns the positions of a custodian account, only CustodianAccountFinancialsSo financials hold them
+func custodianBalances(ca queries.AccountWithBalanceCustodianAccounts) []queries.Balance {
+	if ca.Financials == nil || ca.Financials.CustodianAccountFinancialsSo == nil {
+		return nil
+	}
+	return ca.Financials.CustodianAccountFinancialsSo.Balance
 }
@@ -1,17 +1,26 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/samber/lo"
-	"github.com/vpineda1996/wealthgo/client/graphql/generated"
+	"github.com/vpineda1996/wealthgo/client/graphql/queries"
 )
 
-// GetActivities retrieves account activities
-func (api *WealthsimpleAPI) GetActivities(accountID string, howMany int, orderBy string, ignoreRejected bool) ([]generated.ActivityFeedItem, error) {
+// activitiesPageSize caps the number of activities requested per page
+const activitiesPageSize = 100
+
+// GetActivities retrieves up to howMany account activities, following pagination as needed
+func (api *WealthsimpleAPI) GetActivities(accountID string, howMany int, orderBy string, ignoreRejected bool) ([]queries.Activity, error) {
+	return api.GetActivitiesWithContext(context.Background(), accountID, howMany, orderBy, ignoreRejected)
+}
+
+// GetActivitiesWithContext retrieves account activities, requests are bound to ctx
+func (api *WealthsimpleAPI) GetActivitiesWithContext(ctx context.Context, accountID string, howMany int, orderBy string, ignoreRejected bool) ([]queries.Activity, error) {
 	if orderBy == "" {
 		orderBy = "OCCURRED_AT_DESC"
 	}
@@ -21,25 +30,35 @@ func (api *WealthsimpleAPI) GetActivities(accountID string, howMany int, orderBy
 
 	// Calculate end date
 	endDate := time.Now().Add(time.Hour * 24).Format(time.RFC3339)
-	activities, err := DoGraphQLQuery[[]generated.ActivityFeedItem](
+	activities, err := collectPages(PaginateGraphQLOperation(
+		ctx,
 		&api.WealthsimpleAPIBase,
-		GraphQlQueryOpts{
+		OperationPaginateOpts[queries.FetchActivityFeedItemsResponse, queries.Activity]{
 			QueryName: "FetchActivityFeedItems",
 			Variables: map[string]any{
 				"orderBy": orderBy,
-				"first":   howMany,
+				"first":   min(howMany, activitiesPageSize),
 				"condition": map[string]any{
 					"endDate":    endDate,
 					"accountIds": []string{accountID},
 				},
 			},
-			DataResponsePath: "activityFeedItems.edges",
-			ExpectType:       arrayType,
-		})
-	if err != nil {
+			Connection: func(data *queries.FetchActivityFeedItemsResponse) ([]queries.Activity, *PageInfo) {
+				connection := data.ActivityFeedItems
+				if connection == nil {
+					return nil, nil
+				}
+				nodes := lo.Map(connection.Edges, func(edge queries.FetchActivityFeedItemsActivityFeedItemsEdges, _ int) queries.Activity {
+					return edge.Node
+				})
+				return nodes, &PageInfo{HasNextPage: connection.PageInfo.HasNextPage, EndCursor: lo.FromPtr(connection.PageInfo.EndCursor)}
+			},
+			Limit: howMany,
+		}))
+	if activities == nil && err != nil {
 		return nil, err
 	}
-	filterFn := func(activity generated.ActivityFeedItem, _ int) bool {
+	filterFn := func(activity queries.Activity, _ int) bool {
 		if !ignoreRejected {
 			return true
 		}
@@ -47,11 +66,17 @@ func (api *WealthsimpleAPI) GetActivities(accountID string, howMany int, orderBy
 		return activity.Type != "LEGACY_TRANSFER" || (status != "rejected" && status != "cancelled")
 	}
 	activities = lo.Filter(activities, filterFn)
-	return activities, nil
+	// Partial results are returned along with the GraphQL errors, if any
+	return activities, err
 }
 
 // activityAddDescription adds a description to an activity
-func (api *WealthsimpleAPI) ActivityDescription(activity *generated.ActivityFeedItem) string {
+func (api *WealthsimpleAPI) ActivityDescription(activity *queries.Activity) string {
+	return api.ActivityDescriptionWithContext(context.Background(), activity)
+}
+
+// ActivityDescriptionWithContext builds a description for an activity, symbol lookups are bound to ctx
+func (api *WealthsimpleAPI) ActivityDescriptionWithContext(ctx context.Context, activity *queries.Activity) string {
 
 	// Default description
 	description := fmt.Sprintf("%s: %s", activity.Type, activity.SubType)
@@ -85,7 +110,7 @@ func (api *WealthsimpleAPI) ActivityDescription(activity *generated.ActivityFeed
 		amount := activity.Amount
 
 		if securityID != nil {
-			symbol, err := api.SecurityIDToSymbol(*securityID)
+			symbol, err := api.SecurityIDToSymbolWithContext(ctx, *securityID)
 			if err == nil {
 				qty, _ := strconv.ParseFloat(assetQuantity, 64)
 				amt, _ := strconv.ParseFloat(amount, 64)
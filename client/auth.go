@@ -1,44 +1,80 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 )
 
 // GetTokenInfo retrieves token information
 func (api *WealthsimpleAPIBase) GetTokenInfo() (*TokenInformation, error) {
-	if api.Session.TokenInfo == nil {
-		headers := map[string]any{
-			"x-wealthsimple-client": "@wealthsimple/wealthsimple",
-		}
-		response, err := api.SendGet(fmt.Sprintf("%s/token/info", api.OAuthBaseURL), headers, false)
-		if err != nil {
-			return nil, err
-		}
+	return api.GetTokenInfoWithContext(context.Background())
+}
 
-		b, err := json.Marshal(response)
-		if err != nil {
-			return nil, err
-		}
+// GetTokenInfoWithContext retrieves token information, the request is bound to ctx
+func (api *WealthsimpleAPIBase) GetTokenInfoWithContext(ctx context.Context) (*TokenInformation, error) {
+	if tokenInfo := api.CurrentSession().TokenInfo; tokenInfo != nil {
+		return tokenInfo, nil
+	}
+	return api.fetchTokenInfo(ctx)
+}
 
-		var tokenInfo TokenInformation
-		if err := json.Unmarshal(b, &tokenInfo); err != nil {
-			return nil, err
-		}
+// fetchTokenInfo asks the token endpoint about the access token, which validates it. The expiry it reports
+// is recorded when the session doesn't know it yet
+func (api *WealthsimpleAPIBase) fetchTokenInfo(ctx context.Context) (*TokenInformation, error) {
+	headers := map[string]any{
+		"x-wealthsimple-client": "@wealthsimple/wealthsimple",
+	}
+	response, err := api.SendGetWithContext(ctx, fmt.Sprintf("%s/token/info", api.OAuthBaseURL), headers, false)
+	if err != nil {
+		return nil, err
+	}
 
-		api.Session.TokenInfo = &tokenInfo
+	b, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenInfo TokenInformation
+	if err := json.Unmarshal(b, &tokenInfo); err != nil {
+		return nil, err
 	}
-	return api.Session.TokenInfo, nil
+
+	responseMap, _ := response.(map[string]interface{})
+	// Unlike the token response, expires_in is the time left here
+	remaining, hasExpiry := int64Value(responseMap["expires_in"])
+	api.updateSession(func(s *WSAPISession) {
+		s.TokenInfo = &tokenInfo
+		if hasExpiry && remaining > 0 && s.ExpiresAt().IsZero() {
+			s.CreatedAt, s.ExpiresIn = time.Now().Unix(), remaining
+		}
+	})
+	return &tokenInfo, nil
 }
 
 // Login logs in to the Wealthsimple API
-func Login(username, password, otpAnswer string, persistSessionFct func(string) error, scope string) (*WealthsimpleAPI, error) {
-	api := newWealthsimpleAPI(nil)
+func Login(username, password, otpAnswer string, persistSessionFct func(string) error, scope string, opts ...Option) (*WealthsimpleAPI, error) {
+	return LoginWithContext(context.Background(), username, password, otpAnswer, persistSessionFct, scope, opts...)
+}
+
+// LoginWithContext logs in to the Wealthsimple API, every request made during login is bound to ctx. When
+// a second step is needed, the error is a *LoginChallenge, an OTP challenge is completed with its Submit method
+func LoginWithContext(ctx context.Context, username, password, otpAnswer string, persistSessionFct func(string) error, scope string, opts ...Option) (*WealthsimpleAPI, error) {
+	api, err := newWealthsimpleAPI(ctx, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
 	if scope == "" {
 		scope = api.ScopeReadOnly
 	}
-	_, err := api.LoginInternal(username, password, otpAnswer, persistSessionFct, scope)
+	_, err = api.LoginInternalWithContext(ctx, username, password, otpAnswer, persistSessionFct, scope)
+	var challenge *LoginChallenge
+	if errors.As(err, &challenge) {
+		challenge.client = api
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -47,15 +83,41 @@ func Login(username, password, otpAnswer string, persistSessionFct func(string)
 
 // LoginInternal logs in to the Wealthsimple API
 func (api *WealthsimpleAPIBase) LoginInternal(username, password, otpAnswer string, persistSessionFct func(string) error, scope string) (*WSAPISession, error) {
+	return api.LoginInternalWithContext(context.Background(), username, password, otpAnswer, persistSessionFct, scope)
+}
+
+// LoginInternalWithContext logs in to the Wealthsimple API, the token request is bound to ctx. A second step
+// is reported as a *LoginChallenge error, unless it is an OTP challenge answered with the TOTP secret
+func (api *WealthsimpleAPIBase) LoginInternalWithContext(ctx context.Context, username, password, otpAnswer string, persistSessionFct func(string) error, scope string) (*WSAPISession, error) {
+	credentials := loginCredentials{
+		username:          username,
+		password:          password,
+		scope:             scope,
+		persistSessionFct: persistSessionFct,
+	}
+	session, err := api.login(ctx, credentials, otpAnswer, "")
+	var challenge *LoginChallenge
+	if errors.As(err, &challenge) && challenge.Type == ChallengeOTP && api.totpKey != nil {
+		return api.answerTOTP(ctx, challenge)
+	}
+	return session, err
+}
+
+// login requests a token with the password grant, otpClaim identifies the code otpAnswer answers when the
+// server issued one
+func (api *WealthsimpleAPIBase) login(ctx context.Context, credentials loginCredentials, otpAnswer, otpClaim string) (*WSAPISession, error) {
 	data := map[string]interface{}{
 		"grant_type":     "password",
-		"username":       username,
-		"password":       password,
+		"username":       credentials.username,
+		"password":       credentials.password,
 		"skip_provision": "true",
-		"scope":          scope,
-		"client_id":      api.Session.ClientID,
+		"scope":          credentials.scope,
+		"client_id":      api.CurrentSession().ClientID,
 		"otp_claim":      nil,
 	}
+	if otpClaim != "" {
+		data["otp_claim"] = otpClaim
+	}
 
 	headers := map[string]interface{}{
 		"x-wealthsimple-client": "@wealthsimple/wealthsimple",
@@ -63,16 +125,32 @@ func (api *WealthsimpleAPIBase) LoginInternal(username, password, otpAnswer stri
 	}
 
 	if otpAnswer != "" {
-		headers["x-wealthsimple-otp"] = fmt.Sprintf("%s;remember=true", otpAnswer)
+		headers[otpHeader] = fmt.Sprintf("%s;remember=true", otpAnswer)
 	}
 
 	// Send the POST request for token
-	response, err := api.SendPost(
+	response, err := api.SendPostWithContext(
+		ctx,
 		fmt.Sprintf("%s/token", api.OAuthBaseURL),
 		data,
 		headers,
 		false,
 	)
+
+	// The token endpoint rejects bad credentials with a 4xx status and an OAuth error body
+	var wsErr *WSAPIError
+	if errors.As(err, &wsErr) {
+		if errMsg, ok := wsErr.Response["error"].(string); ok {
+			if challenge := challengeType(errMsg, otpAnswer, wsErr.Header); challenge != "" {
+				return nil, newLoginChallenge(api, credentials, challenge, wsErr)
+			}
+			wsErr.Err = ErrLoginFailed
+			if errMsg == "invalid_grant" && otpAnswer != "" {
+				return nil, fmt.Errorf("%w: %w", ErrOTPInvalid, wsErr)
+			}
+			return nil, wsErr
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -83,10 +161,7 @@ func (api *WealthsimpleAPIBase) LoginInternal(username, password, otpAnswer stri
 	}
 
 	// Check if there was an error
-	if errMsg, ok := responseMap["error"].(string); ok {
-		if errMsg == "invalid_grant" && otpAnswer == "" {
-			return nil, ErrOTPRequired
-		}
+	if _, ok := responseMap["error"].(string); ok {
 		return nil, &WSAPIError{Err: ErrLoginFailed, Response: responseMap}
 	}
 
@@ -101,27 +176,45 @@ func (api *WealthsimpleAPIBase) LoginInternal(username, password, otpAnswer stri
 		return nil, fmt.Errorf("%w: refresh_token not found in response", ErrUnexpected)
 	}
 
-	api.Session.AccessToken = accessToken
-	api.Session.RefreshToken = refreshToken
+	session := api.updateSession(func(s *WSAPISession) {
+		s.AccessToken = accessToken
+		s.RefreshToken = refreshToken
+		s.CreatedAt, s.ExpiresIn = tokenLifetime(responseMap)
+	})
 
-	// Persist the session if a persist function is provided
-	if persistSessionFct != nil {
-		sessionJSON, err := api.Session.ToJSON()
-		if err != nil {
-			return nil, err
-		}
-		if err := persistSessionFct(sessionJSON); err != nil {
-			return nil, err
-		}
+	// Persist the session if a persist function is provided, it is kept for later refreshes
+	if credentials.persistSessionFct != nil {
+		api.setPersistSession(credentials.persistSessionFct)
+	}
+	if err := api.persistSession(ctx, session); err != nil {
+		return nil, err
 	}
 
-	return api.Session, nil
+	return &session, nil
 }
 
 // FromToken creates a new WealthsimpleAPI instance from a session token
-func FromToken(sess *WSAPISession, persistSessionFct func(string) error) (*WealthsimpleAPI, error) {
-	api := newWealthsimpleAPI(sess)
-	if err := api.CheckOAuthToken(persistSessionFct); err != nil {
+func FromToken(sess *WSAPISession, persistSessionFct func(string) error, opts ...Option) (*WealthsimpleAPI, error) {
+	return FromTokenWithContext(context.Background(), sess, persistSessionFct, opts...)
+}
+
+// FromTokenWithContext creates a new WealthsimpleAPI instance from a session token,
+// validating or refreshing the token with requests bound to ctx. A nil sess is loaded from the
+// session store set with WithSessionStore
+func FromTokenWithContext(ctx context.Context, sess *WSAPISession, persistSessionFct func(string) error, opts ...Option) (*WealthsimpleAPI, error) {
+	api, err := NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil && api.SessionStore != nil {
+		if sess, err = api.SessionStore.Load(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if err := api.StartSessionWithContext(ctx, sess); err != nil {
+		return nil, err
+	}
+	if err := api.CheckOAuthTokenWithContext(ctx, persistSessionFct); err != nil {
 		return nil, err
 	}
 	return api, nil
@@ -129,70 +222,178 @@ func FromToken(sess *WSAPISession, persistSessionFct func(string) error) (*Wealt
 
 // CheckOAuthToken checks if the OAuth token is valid and refreshes it if needed
 func (api *WealthsimpleAPIBase) CheckOAuthToken(persistSessionFct func(string) error) error {
-	if api.Session.AccessToken != "" {
-		// Try to use the token
-		_, err := api.SearchSecurity("XEQT")
-		if err == nil {
-			return nil
-		}
+	return api.CheckOAuthTokenWithContext(context.Background(), persistSessionFct)
+}
+
+// CheckOAuthTokenWithContext checks if the OAuth token is valid and refreshes it if needed,
+// requests are bound to ctx. A token whose expiry is known is checked without any request, the
+// token info endpoint validates the others. persistSessionFct, when set, is kept to persist later refreshes
+func (api *WealthsimpleAPIBase) CheckOAuthTokenWithContext(ctx context.Context, persistSessionFct func(string) error) error {
+	if persistSessionFct != nil {
+		api.setPersistSession(persistSessionFct)
+	}
 
-		// Check if the error is due to authorization
-		var wsErr *WSAPIError
-		if errors.As(err, &wsErr) {
-			if msg, ok := wsErr.Response["message"].(string); !ok || msg != "Not Authorized." {
+	session := api.CurrentSession()
+	if session.AccessToken != "" {
+		switch {
+		case session.ExpiresAt().IsZero():
+			// Expiry unknown, let the token endpoint validate the token
+			_, err := api.fetchTokenInfo(ctx)
+			if err == nil {
+				return nil
+			}
+			if !errors.Is(err, ErrNotAuthorized) {
 				return err
 			}
-			// Access token expired; try to refresh it below
-		} else {
-			return err
+		case !session.needsRefresh():
+			return nil
 		}
 	}
 
-	if api.Session.RefreshToken != "" {
-		data := map[string]interface{}{
-			"grant_type":    "refresh_token",
-			"refresh_token": api.Session.RefreshToken,
-			"client_id":     api.Session.ClientID,
-		}
-		headers := map[string]interface{}{
-			"x-wealthsimple-client": "@wealthsimple/wealthsimple",
-			"x-ws-profile":          "invest",
-		}
-		response, err := api.SendPost(fmt.Sprintf("%s/token", api.OAuthBaseURL), data, headers, false)
-		if err != nil {
-			return err
-		}
+	return api.refreshAccessToken(ctx, session.AccessToken)
+}
 
-		responseMap, ok := response.(map[string]interface{})
-		if !ok {
-			return fmt.Errorf("%w: unexpected response type", ErrUnexpected)
-		}
+// RefreshAccessToken exchanges the session refresh token for a new access token and persists the session
+func (api *WealthsimpleAPIBase) RefreshAccessToken(ctx context.Context) error {
+	return api.refreshAccessToken(ctx, "")
+}
 
-		accessToken, ok := responseMap["access_token"].(string)
-		if !ok {
-			return fmt.Errorf("%w: access_token not found in response", ErrUnexpected)
-		}
+// refreshAccessToken refreshes the access token unless it no longer matches staleToken, meaning another
+// goroutine already refreshed it. Concurrent callers are serialized so the refresh token is only used once
+func (api *WealthsimpleAPIBase) refreshAccessToken(ctx context.Context, staleToken string) error {
+	api.refreshMu.Lock()
+	defer api.refreshMu.Unlock()
 
-		refreshToken, ok := responseMap["refresh_token"].(string)
-		if !ok {
-			return fmt.Errorf("%w: refresh_token not found in response", ErrUnexpected)
-		}
+	session := api.CurrentSession()
+	if staleToken != "" && session.AccessToken != staleToken {
+		return nil
+	}
 
-		api.Session.AccessToken = accessToken
-		api.Session.RefreshToken = refreshToken
+	if store, ok := api.SessionStore.(LockingSessionStore); ok {
+		unlock, err := store.Lock(ctx)
+		switch {
+		case errors.Is(err, errors.ErrUnsupported):
+			// Without file locks, concurrent processes may still race to use the refresh token
+			api.logger().WarnContext(ctx, "session store can't be locked on this platform, refreshing without the lock")
+		case err != nil:
+			return fmt.Errorf("locking session store: %w", err)
+		default:
+			defer func() {
+				if err := unlock(); err != nil {
+					api.logger().DebugContext(ctx, "failed to unlock the session store", slog.String("error", err.Error()))
+				}
+			}()
 
-		// Persist the session if a persist function is provided
-		if persistSessionFct != nil {
-			sessionJSON, err := api.Session.ToJSON()
-			if err != nil {
-				return err
+			// Another process may have refreshed the session while we waited for the lock, its tokens replace
+			// ours which are no longer valid
+			stored, err := store.Load(ctx)
+			if err != nil && !errors.Is(err, ErrSessionNotFound) {
+				return fmt.Errorf("loading session: %w", err)
 			}
-			if err := persistSessionFct(sessionJSON); err != nil {
-				return err
+			if stored != nil && stored.RefreshToken != "" && stored.RefreshToken != session.RefreshToken {
+				api.logger().DebugContext(ctx, "session refreshed by another process, reloading it")
+				session = api.updateSession(func(s *WSAPISession) {
+					s.AccessToken = stored.AccessToken
+					s.RefreshToken = stored.RefreshToken
+					s.CreatedAt = stored.CreatedAt
+					s.ExpiresIn = stored.ExpiresIn
+				})
+				if !session.needsRefresh() {
+					return nil
+				}
+				// The stored token is about to expire too, it is refreshed while the lock is still held
+				api.logger().DebugContext(ctx, "reloaded session about to expire, refreshing it", slog.Time("expiresAt", session.ExpiresAt()))
 			}
 		}
-		return nil
 	}
 
-	return fmt.Errorf("%w: OAuth token invalid and cannot be refreshed", ErrManualLogin)
+	if session.RefreshToken == "" {
+		return fmt.Errorf("%w: OAuth token invalid and cannot be refreshed", ErrManualLogin)
+	}
+
+	data := map[string]interface{}{
+		"grant_type":    "refresh_token",
+		"refresh_token": session.RefreshToken,
+		"client_id":     session.ClientID,
+	}
+	headers := map[string]interface{}{
+		"x-wealthsimple-client": "@wealthsimple/wealthsimple",
+		"x-ws-profile":          "invest",
+	}
+	response, err := withRetry(ctx, api, "refresh_token", func() (interface{}, error) {
+		return api.SendPostWithContext(ctx, fmt.Sprintf("%s/token", api.OAuthBaseURL), data, headers, false)
+	})
+	var wsErr *WSAPIError
+	if errors.As(err, &wsErr) && wsErr.Response["error"] == "invalid_grant" {
+		// The refresh token was revoked or already used, the stored session is of no use anymore
+		api.deleteRevokedSession(ctx, session.RefreshToken)
+		return fmt.Errorf("%w: %w", ErrManualLogin, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	responseMap, ok := response.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%w: unexpected response type", ErrUnexpected)
+	}
+
+	accessToken, ok := responseMap["access_token"].(string)
+	if !ok {
+		return fmt.Errorf("%w: access_token not found in response", ErrUnexpected)
+	}
+
+	refreshToken, ok := responseMap["refresh_token"].(string)
+	if !ok {
+		return fmt.Errorf("%w: refresh_token not found in response", ErrUnexpected)
+	}
+
+	session = api.updateSession(func(s *WSAPISession) {
+		s.AccessToken = accessToken
+		s.RefreshToken = refreshToken
+		s.CreatedAt, s.ExpiresIn = tokenLifetime(responseMap)
+	})
+
+	return api.persistSession(ctx, session)
+}
+
+// deleteRevokedSession deletes the stored session if it still holds the rejected refreshToken. A session saved
+// since then, by another client sharing the store, is left alone
+func (api *WealthsimpleAPIBase) deleteRevokedSession(ctx context.Context, refreshToken string) {
+	if api.SessionStore == nil {
+		return
+	}
+	stored, err := api.SessionStore.Load(ctx)
+	if err != nil {
+		if !errors.Is(err, ErrSessionNotFound) {
+			api.logger().DebugContext(ctx, "failed to load the revoked session", slog.String("error", err.Error()))
+		}
+		return
+	}
+	if stored.RefreshToken != refreshToken {
+		api.logger().DebugContext(ctx, "stored session was replaced, keeping it")
+		return
+	}
+	if err := api.SessionStore.Delete(ctx); err != nil {
+		api.logger().DebugContext(ctx, "failed to delete the revoked session", slog.String("error", err.Error()))
+	}
+}
+
+// persistSession saves session to the session store and hands it to the persist callback, if any
+func (api *WealthsimpleAPIBase) persistSession(ctx context.Context, session WSAPISession) error {
+	if api.SessionStore != nil {
+		if err := api.SessionStore.Save(ctx, &session); err != nil {
+			return fmt.Errorf("saving session: %w", err)
+		}
+	}
+
+	persistSessionFct := api.persistSessionFct()
+	if persistSessionFct == nil {
+		return nil
+	}
+	sessionJSON, err := session.ToJSON()
+	if err != nil {
+		return err
+	}
+	return persistSessionFct(sessionJSON)
 }
@@ -0,0 +1,300 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vpineda1996/wealthgo/client/graphql/queries"
+)
+
+// MaxBatchSize caps how many aliased fields are sent in a single batched request
+const MaxBatchSize = 50
+
+var (
+	operationHeaderRe = regexp.MustCompile(`^\s*query\s+(\w+)\s*(?:\(([^)]*)\))?\s*\{`)
+	variableRe        = regexp.MustCompile(`\$(\w+)`)
+)
+
+// batchAlias returns the alias of the i-th field of a batched query
+func batchAlias(i int) string {
+	return fmt.Sprintf("b%d", i)
+}
+
+// buildBatchQuery rewrites a single root field operation into one query selecting that field once per
+// variables set, every copy aliased and bound to its own renamed variables
+func buildBatchQuery(queryName, document string, count int) (string, error) {
+	header := operationHeaderRe.FindStringSubmatchIndex(document)
+	if header == nil {
+		return "", fmt.Errorf("%w: %s is not a named query", ErrUnexpected, queryName)
+	}
+	variableDefs := ""
+	if header[4] >= 0 {
+		variableDefs = document[header[4]:header[5]]
+	}
+
+	bodyStart := header[1]
+	bodyEnd := matchingBrace(document, bodyStart-1)
+	if bodyEnd < 0 {
+		return "", fmt.Errorf("%w: unbalanced braces in %s", ErrUnexpected, queryName)
+	}
+	field := strings.TrimSpace(document[bodyStart:bodyEnd])
+	fragments := document[bodyEnd+1:]
+
+	// Only a single root field can be aliased, and fragments can't follow the variable renaming
+	fieldOpen := strings.IndexByte(field, '{')
+	if fieldOpen < 0 || matchingBrace(field, fieldOpen) != len(field)-1 {
+		return "", fmt.Errorf("%w: %s must select a single root field to be batched", ErrUnexpected, queryName)
+	}
+	if strings.Contains(fragments, "$") {
+		return "", fmt.Errorf("%w: fragments of %s use variables and can't be batched", ErrUnexpected, queryName)
+	}
+
+	var defs, fields []string
+	for i := 0; i < count; i++ {
+		rename := func(m string) string { return fmt.Sprintf("%s_%d", m, i) }
+		if variableDefs != "" {
+			defs = append(defs, variableRe.ReplaceAllStringFunc(variableDefs, rename))
+		}
+		fields = append(fields, batchAlias(i)+": "+variableRe.ReplaceAllStringFunc(field, rename))
+	}
+
+	var b strings.Builder
+	b.WriteString("query " + queryName + "Batch")
+	if len(defs) > 0 {
+		b.WriteString("(" + strings.Join(defs, ", ") + ")")
+	}
+	b.WriteString(" {\n  " + strings.Join(fields, "\n  ") + "\n}")
+	b.WriteString(fragments)
+	return b.String(), nil
+}
+
+// matchingBrace returns the index of the brace closing the one at open, or -1
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// DoGraphQLBatch runs the registered single root field operation queryName once per variables set in a single
+// HTTP round trip, using an aliased multi-field query. Results are returned in the order of variables, a nil
+// entry means the field resolved to null. Partial results are returned along with a GraphQLErrors error
+func DoGraphQLBatch[T any](ctx context.Context, api *WealthsimpleAPIBase, queryName string, variables []map[string]any) ([]*T, error) {
+	if len(variables) == 0 {
+		return nil, nil
+	}
+	if len(variables) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: batch of %d exceeds the maximum of %d", ErrUnexpected, len(variables), MaxBatchSize)
+	}
+
+	document, ok := api.Query(queryName)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown GraphQL operation %s", ErrUnexpected, queryName)
+	}
+	batchQuery, err := buildBatchQuery(queryName, document, len(variables))
+	if err != nil {
+		return nil, err
+	}
+
+	batchVariables := make(map[string]any)
+	for i, vars := range variables {
+		for k, v := range vars {
+			batchVariables[fmt.Sprintf("%s_%d", k, i)] = v
+		}
+	}
+
+	query := map[string]any{
+		"operationName": queryName + "Batch",
+		"query":         batchQuery,
+		"variables":     batchVariables,
+	}
+	response, err := postGraphQL[map[string]*T](ctx, api, query, api.graphQLHeaders(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*T, len(variables))
+	for i := range variables {
+		results[i] = (*response.Data)[batchAlias(i)]
+	}
+	if len(response.Errors) > 0 {
+		return results, response.Errors
+	}
+	return results, nil
+}
+
+// GetSecuritiesMarketData retrieves market data for several securities, batching the lookups
+func (api *WealthsimpleAPI) GetSecuritiesMarketData(securityIDs []string, useCache bool) (map[string]*queries.SecurityMarketData, error) {
+	return api.GetSecuritiesMarketDataWithContext(context.Background(), securityIDs, useCache)
+}
+
+// GetSecuritiesMarketDataWithContext retrieves market data for several securities, cache misses are fetched
+// in batches of up to MaxBatchSize securities per request. Requests are bound to ctx
+func (api *WealthsimpleAPI) GetSecuritiesMarketDataWithContext(ctx context.Context, securityIDs []string, useCache bool) (map[string]*queries.SecurityMarketData, error) {
+	result := make(map[string]*queries.SecurityMarketData, len(securityIDs))
+
+	var missing []string
+	for _, securityID := range securityIDs {
+		if _, ok := result[securityID]; ok {
+			continue
+		}
+		if useCache && api.SecurityMarketDataCacheGetter != nil {
+			if cachedValue, ok := api.SecurityMarketDataCacheGetter(securityID); ok && cachedValue != nil {
+				result[securityID] = cachedValue
+				continue
+			}
+		}
+		result[securityID] = nil
+		missing = append(missing, securityID)
+	}
+
+	// Securities that can't be resolved don't stop the other chunks, their errors are returned together
+	var gqlErrs GraphQLErrors
+	for start := 0; start < len(missing); start += MaxBatchSize {
+		chunk := missing[start:min(start+MaxBatchSize, len(missing))]
+		variables := make([]map[string]any, len(chunk))
+		for i, securityID := range chunk {
+			variables[i] = map[string]any{"id": securityID}
+		}
+
+		securities, err := DoGraphQLBatch[queries.SecurityMarketData](ctx, &api.WealthsimpleAPIBase, "FetchSecurityMarketData", variables)
+		if securities == nil {
+			return nil, err
+		}
+		for i, securityID := range chunk {
+			result[securityID] = securities[i]
+			if securities[i] != nil && useCache && api.SecurityMarketDataCacheSetter != nil {
+				api.SecurityMarketDataCacheSetter(securityID, securities[i])
+			}
+		}
+		var batchErrs GraphQLErrors
+		if errors.As(err, &batchErrs) {
+			gqlErrs = append(gqlErrs, batchErrs...)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	for securityID, security := range result {
+		if security == nil {
+			delete(result, securityID)
+		}
+	}
+	if len(gqlErrs) > 0 {
+		return result, gqlErrs
+	}
+	return result, nil
+}
+
+// securityResult is the outcome of a coalesced market data lookup
+type securityResult struct {
+	security *queries.SecurityMarketData
+	err      error
+}
+
+// securityLoader coalesces concurrent market data lookups issued within a short window into a single
+// batched request, in the spirit of a dataloader
+type securityLoader struct {
+	api      *WealthsimpleAPI
+	window   time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	pending map[string][]chan securityResult
+	timer   *time.Timer
+}
+
+// newSecurityLoader creates a loader flushing lookups after window or once maxBatch ids are pending
+func newSecurityLoader(api *WealthsimpleAPI, window time.Duration, maxBatch int) *securityLoader {
+	if maxBatch <= 0 || maxBatch > MaxBatchSize {
+		maxBatch = MaxBatchSize
+	}
+	return &securityLoader{
+		api:      api,
+		window:   window,
+		maxBatch: maxBatch,
+		pending:  make(map[string][]chan securityResult),
+	}
+}
+
+// load queues a lookup and waits for the batch holding it to complete
+func (l *securityLoader) load(ctx context.Context, securityID string) (*queries.SecurityMarketData, error) {
+	ch := make(chan securityResult, 1)
+
+	l.mu.Lock()
+	l.pending[securityID] = append(l.pending[securityID], ch)
+	if len(l.pending) >= l.maxBatch {
+		batch := l.takeLocked()
+		l.mu.Unlock()
+		go l.flush(batch)
+	} else {
+		if l.timer == nil {
+			l.timer = time.AfterFunc(l.window, func() {
+				l.mu.Lock()
+				batch := l.takeLocked()
+				l.mu.Unlock()
+				l.flush(batch)
+			})
+		}
+		l.mu.Unlock()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		return res.security, res.err
+	}
+}
+
+// takeLocked detaches the pending lookups, l.mu must be held
+func (l *securityLoader) takeLocked() map[string][]chan securityResult {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	batch := l.pending
+	l.pending = make(map[string][]chan securityResult)
+	return batch
+}
+
+// flush fetches a batch of lookups and hands every waiter its result
+func (l *securityLoader) flush(batch map[string][]chan securityResult) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(batch))
+	for securityID := range batch {
+		ids = append(ids, securityID)
+	}
+
+	// The batch is shared by several callers, none of their contexts may cancel it for the others
+	securities, err := l.api.GetSecuritiesMarketDataWithContext(context.Background(), ids, false)
+	for securityID, waiters := range batch {
+		res := securityResult{security: securities[securityID]}
+		if res.security == nil {
+			res.err = err
+			if res.err == nil {
+				res.err = fmt.Errorf("%w: security %s not found", ErrUnexpected, securityID)
+			}
+		}
+		for _, ch := range waiters {
+			ch <- res
+		}
+	}
+}
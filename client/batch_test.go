@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vpineda1996/wealthgo/client/graphql/queries"
+)
+
+func TestBuildBatchQuery(t *testing.T) {
+	document := `query FetchSecurity($id: ID!, $currency: String) {
+  security(id: $id) {
+    ...SecurityFields
+    quote(currency: $currency) { last }
+  }
+}
+
+fragment SecurityFields on Security {
+  id
+}`
+
+	got, err := buildBatchQuery("FetchSecurity", document, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `query FetchSecurityBatch($id_0: ID!, $currency_0: String, $id_1: ID!, $currency_1: String) {
+  b0: security(id: $id_0) {
+    ...SecurityFields
+    quote(currency: $currency_0) { last }
+  }
+  b1: security(id: $id_1) {
+    ...SecurityFields
+    quote(currency: $currency_1) { last }
+  }
+}
+
+fragment SecurityFields on Security {
+  id
+}`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBuildBatchQueryRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+	}{
+		{"anonymous query", `{ security(id: "sec-1") { id } }`},
+		{"mutation", `mutation Cancel($id: ID!) { cancelOrder(id: $id) { id } }`},
+		{"several root fields", `query Fetch($id: ID!) { security(id: $id) { id } identity { id } }`},
+		{"fragment with variables", `query Fetch($id: ID!) { security(id: $id) { ...F } } fragment F on Security { quote(currency: $c) { last } }`},
+		{"unbalanced braces", `query Fetch($id: ID!) { security(id: $id) { id }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildBatchQuery("Fetch", tt.document, 2); !errors.Is(err, ErrUnexpected) {
+				t.Errorf("err = %v, want ErrUnexpected", err)
+			}
+		})
+	}
+}
+
+// newSecuritiesAPI answers FetchSecurityMarketDataBatch with the security of every aliased id, ids starting with
+// "missing" resolve to null along with an error. It records the ids of every batch
+func newSecuritiesAPI(t *testing.T, opts ...Option) (*WealthsimpleAPI, func() [][]string) {
+	f := newFakeAPI(t)
+	var mu sync.Mutex
+	var batches [][]string
+	f.handle("FetchSecurityMarketDataBatch", func(w http.ResponseWriter, req *fakeRequest) {
+		if !strings.HasPrefix(req.Query, "query FetchSecurityMarketDataBatch(") {
+			t.Errorf("unexpected batch query %s", req.Query)
+		}
+
+		data := make(map[string]any)
+		var errs []map[string]any
+		var ids []string
+		for i := 0; ; i++ {
+			id, ok := req.Variables[fmt.Sprintf("id_%d", i)].(string)
+			if !ok {
+				break
+			}
+			ids = append(ids, id)
+			alias := batchAlias(i)
+			if strings.HasPrefix(id, "missing") {
+				data[alias] = nil
+				errs = append(errs, map[string]any{"message": "Security not found", "path": []any{alias}})
+				continue
+			}
+			data[alias] = map[string]any{"id": id}
+		}
+		mu.Lock()
+		batches = append(batches, ids)
+		mu.Unlock()
+
+		body, _ := json.Marshal(map[string]any{"data": data, "errors": errs})
+		writeJSON(w, http.StatusOK, string(body))
+	})
+	return f.newClient(opts...), func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return batches
+	}
+}
+
+func TestDoGraphQLBatch(t *testing.T) {
+	api, batches := newSecuritiesAPI(t)
+	variables := []map[string]any{{"id": "sec-a"}, {"id": "missing-b"}, {"id": "sec-c"}}
+
+	securities, err := DoGraphQLBatch[queries.SecurityMarketData](context.Background(), &api.WealthsimpleAPIBase, "FetchSecurityMarketData", variables)
+	var gqlErrs GraphQLErrors
+	if !errors.As(err, &gqlErrs) || gqlErrs[0].PathString() != "b1" {
+		t.Errorf("err = %v, want the error of b1", err)
+	}
+	if len(securities) != 3 {
+		t.Fatalf("got %d results, want 3", len(securities))
+	}
+	if securities[0].Id != "sec-a" || securities[1] != nil || securities[2].Id != "sec-c" {
+		t.Errorf("results out of order: %v %v %v", securities[0], securities[1], securities[2])
+	}
+	if got := batches(); len(got) != 1 {
+		t.Errorf("sent %d requests, want 1", len(got))
+	}
+}
+
+func TestDoGraphQLBatchTooLarge(t *testing.T) {
+	api, batches := newSecuritiesAPI(t)
+	variables := make([]map[string]any, MaxBatchSize+1)
+
+	if _, err := DoGraphQLBatch[queries.SecurityMarketData](context.Background(), &api.WealthsimpleAPIBase, "FetchSecurityMarketData", variables); !errors.Is(err, ErrUnexpected) {
+		t.Errorf("err = %v, want ErrUnexpected", err)
+	}
+	if len(batches()) != 0 {
+		t.Error("oversized batch was sent")
+	}
+}
+
+func TestGetSecuritiesMarketDataChunksAndCaches(t *testing.T) {
+	var cached atomic.Int32
+	api, batches := newSecuritiesAPI(t,
+		WithSecurityMarketDataCache(
+			func(id string) (*queries.SecurityMarketData, bool) {
+				return &queries.SecurityMarketData{Id: id}, id == "cached"
+			},
+			func(id string, _ *queries.SecurityMarketData) { cached.Add(1) },
+		))
+
+	ids := []string{"cached", "missing-1"}
+	for i := range MaxBatchSize + 5 {
+		ids = append(ids, fmt.Sprintf("sec-%d", i))
+	}
+	securities, err := api.GetSecuritiesMarketData(ids, true)
+	if !errors.As(err, new(GraphQLErrors)) {
+		t.Errorf("err = %v, want the error of missing-1", err)
+	}
+	if _, ok := securities["missing-1"]; ok {
+		t.Error("missing security is in the result")
+	}
+	if len(securities) != MaxBatchSize+6 {
+		t.Errorf("got %d securities, want %d", len(securities), MaxBatchSize+6)
+	}
+
+	sent := batches()
+	if len(sent) != 2 || len(sent[0]) != MaxBatchSize || len(sent[1]) != 6 {
+		t.Errorf("sent batches of %v ids, want %d and 6", lengths(sent), MaxBatchSize)
+	}
+	if got := cached.Load(); got != MaxBatchSize+5 {
+		t.Errorf("cached %d securities, want %d", got, MaxBatchSize+5)
+	}
+}
+
+func TestMarketDataBatching(t *testing.T) {
+	api, batches := newSecuritiesAPI(t, WithMarketDataBatching(20*time.Millisecond, 10))
+
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := fmt.Sprintf("sec-%d", i)
+			security, err := api.GetSecurityMarketDataWithContext(context.Background(), id, false)
+			if err != nil {
+				t.Error(err)
+			} else if security.Id != id {
+				t.Errorf("got security %s, want %s", security.Id, id)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := batches(); len(got) != 1 || len(got[0]) != 5 {
+		t.Errorf("sent batches of %v ids, want a single one of 5", lengths(got))
+	}
+}
+
+func lengths(batches [][]string) []int {
+	n := make([]int, len(batches))
+	for i, batch := range batches {
+		n[i] = len(batch)
+	}
+	return n
+}
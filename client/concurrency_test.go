@@ -0,0 +1,77 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+const accountsResponse = `{"data":{"identity":{"id":"identity-1","accounts":{
+	"pageInfo":{"hasNextPage":false,"endCursor":"c2"},
+	"edges":[
+		{"cursor":"c1","node":{"id":"tfsa-1","status":"open","createdAt":"2024-01-02"}},
+		{"cursor":"c2","node":{"id":"rrsp-1","status":"closed","createdAt":"2024-01-02"}}
+	]}}}}`
+
+// TestConcurrentRequests runs queries from many goroutines while the access token is rejected, run it with
+// -race. Every request must succeed after a single token refresh
+func TestConcurrentRequests(t *testing.T) {
+	f := newFakeAPI(t)
+	f.respond("FetchAllAccountFinancials", accountsResponse)
+	f.handle("FetchSecurityMarketData", func(w http.ResponseWriter, req *fakeRequest) {
+		writeJSON(w, http.StatusOK, fmt.Sprintf(`{"data":{"security":{"id":%q}}}`, req.Variables["id"]))
+	})
+	api := f.newClient(WithSession(&WSAPISession{
+		AccessToken:  "access-0",
+		RefreshToken: "refresh-0",
+		TokenInfo:    &TokenInformation{IdentityCanonicalId: "identity-1"},
+	}))
+
+	// The server now only accepts a token issued by a refresh
+	f.mu.Lock()
+	f.accessToken = "revoked"
+	f.mu.Unlock()
+
+	const goroutines = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, 3*goroutines)
+	for i := range goroutines {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			accounts, err := api.GetAccounts(i%2 == 0, false)
+			if err == nil && len(accounts) == 0 {
+				err = fmt.Errorf("no accounts returned")
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			securityID := fmt.Sprintf("sec-%d", i)
+			security, err := api.GetSecurityMarketData(securityID, false)
+			if err == nil && security.Id != securityID {
+				err = fmt.Errorf("got security %s, want %s", security.Id, securityID)
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- api.CheckOAuthToken(nil)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if got := f.tokenRequests.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want 1", got)
+	}
+	if accessToken, _ := f.tokens(); api.CurrentSession().AccessToken != accessToken {
+		t.Errorf("client holds %s, server issued %s", api.CurrentSession().AccessToken, accessToken)
+	}
+}
@@ -1,28 +1,62 @@
 package client
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
 )
 
 // Error types
 var (
-	ErrCurl          = errors.New("curl error")
-	ErrLoginFailed   = errors.New("login failed")
-	ErrManualLogin   = errors.New("manual login required")
-	ErrOTPRequired   = errors.New("OTP required")
-	ErrUnexpected    = errors.New("unexpected error")
-	ErrWSApi         = errors.New("WS API error")
-	ErrNotAuthorized = errors.New("not authorized")
+	ErrCurl        = errors.New("curl error")
+	ErrLoginFailed = errors.New("login failed")
+	ErrManualLogin = errors.New("manual login required")
+	ErrOTPRequired = errors.New("OTP required")
+	ErrOTPInvalid  = errors.New("OTP rejected")
+	// ErrChallengeRequired is matched by login challenges other than OTP, which can't be answered through the API
+	ErrChallengeRequired = errors.New("login challenge required")
+	ErrUnexpected        = errors.New("unexpected error")
+	ErrWSApi             = errors.New("WS API error")
+	ErrNotAuthorized     = errors.New("not authorized")
+	ErrForbidden         = errors.New("forbidden")
+	ErrRateLimited       = errors.New("rate limited")
+	ErrServerError       = errors.New("server error")
+	ErrMaintenance       = errors.New("service under maintenance")
 )
 
+// notAuthorizedMessage is the message the GraphQL API returns for an expired or invalid token
+const notAuthorizedMessage = "Not Authorized."
+
+// maxErrorBodySize bounds how much of an error response is kept in memory
+const maxErrorBodySize = 1 << 20
+
+// maxErrorBodySnippet bounds how much of a non JSON error body is kept on the error
+const maxErrorBodySnippet = 512
+
 // WSAPIError represents an error with additional response data
 type WSAPIError struct {
 	Err      error
 	Response map[string]interface{}
+
+	// StatusCode is the HTTP status of the response, zero when the error was not caused by one
+	StatusCode int
+	// Header holds the response headers
+	Header http.Header
+	// RequestID is the server side request identifier, useful when reporting issues
+	RequestID string
+	// Body holds the beginning of the response body when it couldn't be decoded as JSON
+	Body string
+	// GraphQLErrors holds the parsed "errors" array of a GraphQL response
+	GraphQLErrors GraphQLErrors
 }
 
 func (e *WSAPIError) Error() string {
+	if len(e.GraphQLErrors) > 0 {
+		return fmt.Sprintf("%v: %v", e.Err, e.GraphQLErrors)
+	}
 	if e.Response != nil {
 		if msg, ok := e.Response["message"].(string); ok {
 			return fmt.Sprintf("%v: %s", e.Err, msg)
@@ -32,5 +66,77 @@ func (e *WSAPIError) Error() string {
 			return fmt.Sprintf("%v: %v", e.Err, e.Response)
 		}
 	}
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%v: HTTP %d %s", e.Err, e.StatusCode, http.StatusText(e.StatusCode))
+	}
 	return e.Err.Error()
 }
+
+// Unwrap exposes the wrapped error along with the sentinel matching the HTTP status,
+// so errors.Is(err, ErrRateLimited) and friends work on any WSAPIError
+func (e *WSAPIError) Unwrap() []error {
+	errs := []error{e.Err}
+	errs = append(errs, statusErrors(e.StatusCode)...)
+	if msg, ok := e.Response["message"].(string); ok && msg == notAuthorizedMessage && e.StatusCode != http.StatusUnauthorized {
+		errs = append(errs, ErrNotAuthorized)
+	}
+	if len(e.GraphQLErrors) > 0 {
+		errs = append(errs, e.GraphQLErrors)
+	}
+	return errs
+}
+
+// statusErrors maps an HTTP status code to its sentinel errors
+func statusErrors(statusCode int) []error {
+	switch {
+	case statusCode == http.StatusUnauthorized:
+		return []error{ErrNotAuthorized}
+	case statusCode == http.StatusForbidden:
+		return []error{ErrForbidden}
+	case statusCode == http.StatusTooManyRequests:
+		return []error{ErrRateLimited}
+	case statusCode == http.StatusServiceUnavailable:
+		return []error{ErrMaintenance, ErrServerError}
+	case statusCode >= 500:
+		return []error{ErrServerError}
+	}
+	return nil
+}
+
+// newHTTPError builds a WSAPIError out of a non successful response, the body is consumed
+func newHTTPError(resp *http.Response) *WSAPIError {
+	wsErr := &WSAPIError{
+		Err:        ErrWSApi,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		RequestID:  requestID(resp.Header),
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil {
+		return wsErr
+	}
+
+	var response map[string]interface{}
+	if err := decodeJSON(bytes.NewReader(body), &response); err == nil {
+		wsErr.Response = response
+		return wsErr
+	}
+
+	snippet := strings.TrimSpace(string(body))
+	if len(snippet) > maxErrorBodySnippet {
+		snippet = snippet[:maxErrorBodySnippet]
+	}
+	wsErr.Body = snippet
+	return wsErr
+}
+
+// requestID extracts the server request identifier from the response headers
+func requestID(header http.Header) string {
+	for _, key := range []string{"X-Request-Id", "X-Ws-Request-Id", "X-Amzn-Requestid"} {
+		if id := header.Get(key); id != "" {
+			return id
+		}
+	}
+	return ""
+}
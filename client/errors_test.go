@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// statusSentinels are the errors a WSAPIError may match depending on its response
+var statusSentinels = []error{ErrNotAuthorized, ErrForbidden, ErrRateLimited, ErrServerError, ErrMaintenance}
+
+func TestHTTPErrorStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   []error
+	}{
+		{"bad request", http.StatusBadRequest, `{"error":"invalid_request"}`, nil},
+		{"not authorized message", http.StatusBadRequest, `{"message":"Not Authorized."}`, []error{ErrNotAuthorized}},
+		{"unauthorized", http.StatusUnauthorized, `{"error":"invalid_token"}`, []error{ErrNotAuthorized}},
+		{"forbidden", http.StatusForbidden, `{}`, []error{ErrForbidden}},
+		{"not found", http.StatusNotFound, `{}`, nil},
+		{"too many requests", http.StatusTooManyRequests, `{}`, []error{ErrRateLimited}},
+		{"internal server error", http.StatusInternalServerError, `{}`, []error{ErrServerError}},
+		{"bad gateway", http.StatusBadGateway, `{}`, []error{ErrServerError}},
+		{"service unavailable", http.StatusServiceUnavailable, `{}`, []error{ErrServerError, ErrMaintenance}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, tt.status, tt.body)
+			}))
+			defer s.Close()
+			api, err := NewClient(WithRetryPolicy(NoRetryPolicy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response, err := api.SendGetWithContext(context.Background(), s.URL, nil, false)
+			if response != nil {
+				t.Errorf("response = %v, want none", response)
+			}
+			var wsErr *WSAPIError
+			if !errors.As(err, &wsErr) || wsErr.StatusCode != tt.status {
+				t.Fatalf("err = %v, want a WSAPIError with status %d", err, tt.status)
+			}
+			if !errors.Is(err, ErrWSApi) {
+				t.Error("error doesn't match ErrWSApi")
+			}
+			var got []error
+			for _, sentinel := range statusSentinels {
+				if errors.Is(err, sentinel) {
+					got = append(got, sentinel)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("matches %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorRequestID(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"X-Request-Id", "req-1"},
+		{"X-Ws-Request-Id", "req-1"},
+		{"X-Amzn-RequestId", "req-1"},
+		{"X-Trace-Id", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			recorder.Header().Set(tt.header, "req-1")
+			recorder.WriteHeader(http.StatusInternalServerError)
+
+			if got := newHTTPError(recorder.Result()).RequestID; got != tt.want {
+				t.Errorf("RequestID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorBody(t *testing.T) {
+	page := "<html>" + strings.Repeat("maintenance ", 100) + "</html>"
+	tests := []struct {
+		name     string
+		body     string
+		wantBody string
+		wantJSON bool
+	}{
+		{"JSON", `{"message":"Internal error"}`, "", true},
+		{"text", "  upstream connect error\n", "upstream connect error", false},
+		{"truncated", page, page[:maxErrorBodySnippet], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			recorder.WriteHeader(http.StatusBadGateway)
+			recorder.WriteString(tt.body)
+
+			wsErr := newHTTPError(recorder.Result())
+			if wsErr.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", wsErr.Body, tt.wantBody)
+			}
+			if (wsErr.Response != nil) != tt.wantJSON {
+				t.Errorf("Response = %v", wsErr.Response)
+			}
+		})
+	}
+}
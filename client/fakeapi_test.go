@@ -0,0 +1,185 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeAPI is an httptest stand-in for the OAuth and GraphQL endpoints. The token endpoint rotates the
+// tokens on every refresh, the GraphQL endpoint rejects any other access token than the last one issued and
+// hands the requests to the handler registered for their operation
+type fakeAPI struct {
+	t      testing.TB
+	server *httptest.Server
+
+	mu           sync.Mutex
+	operations   map[string]fakeOperation
+	accessToken  string
+	refreshToken string
+	issued       int
+	// tokenResponse adds members to the refresh responses, e.g. expires_in
+	tokenResponse map[string]any
+	// tokenInfoResponse adds members to the /token/info responses
+	tokenInfoResponse map[string]any
+
+	tokenRequests   atomic.Int32
+	graphQLRequests atomic.Int32
+}
+
+// fakeOperation answers a GraphQL request
+type fakeOperation func(w http.ResponseWriter, req *fakeRequest)
+
+// fakeRequest is a decoded GraphQL request
+type fakeRequest struct {
+	OperationName string         `json:"operationName"`
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	Extensions    map[string]any `json:"extensions"`
+	Header        http.Header    `json:"-"`
+}
+
+func newFakeAPI(t testing.TB) *fakeAPI {
+	f := &fakeAPI{
+		t:            t,
+		operations:   make(map[string]fakeOperation),
+		accessToken:  "access-0",
+		refreshToken: "refresh-0",
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", f.serveToken)
+	mux.HandleFunc("GET /token/info", f.serveTokenInfo)
+	mux.HandleFunc("POST /graphql", f.serveGraphQL)
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// handle registers the handler of operationName
+func (f *fakeAPI) handle(operationName string, op fakeOperation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.operations[operationName] = op
+}
+
+// respond registers an operation always answered with body
+func (f *fakeAPI) respond(operationName, body string) {
+	f.handle(operationName, func(w http.ResponseWriter, req *fakeRequest) {
+		writeJSON(w, http.StatusOK, body)
+	})
+}
+
+// tokens returns the access and refresh tokens currently accepted
+func (f *fakeAPI) tokens() (accessToken, refreshToken string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.accessToken, f.refreshToken
+}
+
+// newClient creates a client of the stand-in, resuming the session access-0/refresh-0 unless opts set another
+func (f *fakeAPI) newClient(opts ...Option) *WealthsimpleAPI {
+	f.t.Helper()
+	opts = append([]Option{
+		WithOAuthBaseURL(f.server.URL),
+		WithGraphQLURL(f.server.URL + "/graphql"),
+		WithSession(&WSAPISession{AccessToken: "access-0", RefreshToken: "refresh-0"}),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Multiplier: 2}),
+	}, opts...)
+	api, err := NewClient(opts...)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return api
+}
+
+func (f *fakeAPI) serveToken(w http.ResponseWriter, r *http.Request) {
+	f.tokenRequests.Add(1)
+	var form map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		writeJSON(w, http.StatusBadRequest, `{"error":"invalid_request"}`)
+		return
+	}
+	if form["grant_type"] != "refresh_token" {
+		writeJSON(w, http.StatusBadRequest, `{"error":"unsupported_grant_type"}`)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if form["refresh_token"] != f.refreshToken {
+		writeJSON(w, http.StatusBadRequest, `{"error":"invalid_grant","error_description":"The provided authorization grant is invalid"}`)
+		return
+	}
+	f.issued++
+	f.accessToken = fmt.Sprintf("access-%d", f.issued)
+	f.refreshToken = fmt.Sprintf("refresh-%d", f.issued)
+
+	response := map[string]any{"access_token": f.accessToken, "refresh_token": f.refreshToken}
+	for k, v := range f.tokenResponse {
+		response[k] = v
+	}
+	body, _ := json.Marshal(response)
+	writeJSON(w, http.StatusOK, string(body))
+}
+
+func (f *fakeAPI) serveTokenInfo(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, `{"error":"invalid_token"}`)
+		return
+	}
+	response := map[string]any{"identity_canonical_id": "identity-1", "application_uid": "app"}
+	for k, v := range f.tokenInfoResponse {
+		response[k] = v
+	}
+	body, _ := json.Marshal(response)
+	writeJSON(w, http.StatusOK, string(body))
+}
+
+func (f *fakeAPI) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	f.graphQLRequests.Add(1)
+	if !f.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, `{"message":"Not Authorized."}`)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		f.t.Error(err)
+		return
+	}
+	req := &fakeRequest{Header: r.Header}
+	if err := json.Unmarshal(body, req); err != nil {
+		f.t.Errorf("invalid GraphQL request %s: %v", body, err)
+		writeJSON(w, http.StatusBadRequest, `{"message":"invalid request"}`)
+		return
+	}
+
+	f.mu.Lock()
+	op := f.operations[req.OperationName]
+	f.mu.Unlock()
+	if op == nil {
+		f.t.Errorf("unexpected GraphQL operation %q", req.OperationName)
+		writeJSON(w, http.StatusBadRequest, `{"message":"unknown operation"}`)
+		return
+	}
+	op(w, req)
+}
+
+// authorized reports whether r carries the access token last issued
+func (f *fakeAPI) authorized(r *http.Request) bool {
+	accessToken, _ := f.tokens()
+	return r.Header.Get("Authorization") == "Bearer "+accessToken
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, strings.TrimSpace(body))
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// lockPollInterval is how often a lock held by another process is tried again
+const lockPollInterval = 50 * time.Millisecond
+
+// lockFile takes an exclusive lock on path, creating the file if needed. It waits for other processes to
+// release the lock until ctx is done, the returned function releases it
+func lockFile(ctx context.Context, path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, sessionFileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if locked {
+			return func() error {
+				err := unlockFile(f)
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
+				}
+				return err
+			}, nil
+		}
+
+		timer := time.NewTimer(lockPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			f.Close()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
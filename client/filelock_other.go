@@ -0,0 +1,18 @@
+//go:build !unix && !windows
+
+package client
+
+import (
+	"errors"
+	"os"
+)
+
+// tryLockFile reports that file locking isn't available on this platform
+func tryLockFile(f *os.File) (bool, error) {
+	return false, errors.ErrUnsupported
+}
+
+// unlockFile is never called since tryLockFile always fails
+func unlockFile(f *os.File) error {
+	return errors.ErrUnsupported
+}
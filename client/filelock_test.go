@@ -0,0 +1,118 @@
+//go:build unix || windows
+
+package client
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockFileExcludes(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "session.json.lock")
+
+	unlock, err := lockFile(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		unlock, err := lockFile(ctx, path)
+		if err == nil {
+			err = unlock()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case err := <-acquired:
+		t.Fatalf("lock taken twice (%v)", err)
+	case <-time.After(3 * lockPollInterval):
+	}
+
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lock not taken after it was released")
+	}
+}
+
+func TestLockFileContextDone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json.lock")
+	unlock, err := lockFile(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*lockPollInterval)
+	defer cancel()
+	if _, err := lockFile(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestSessionStoresShareLock(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	unlock, err := NewFileSessionStore(path).Lock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 2*lockPollInterval)
+	defer cancel()
+	if _, err := NewEncryptedSessionStore(path, "passphrase").Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the lock held through the other store", err)
+	}
+}
+
+// TestRefreshSharedFileStore runs two clients sharing a session file, as two processes would. Only one of them
+// uses the refresh token, the other one adopts the session it saved
+func TestRefreshSharedFileStore(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeAPI(t)
+	path := filepath.Join(t.TempDir(), "session.json")
+	session := &WSAPISession{AccessToken: "access-0", RefreshToken: "refresh-0"}
+	if err := NewFileSessionStore(path).Save(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+
+	clients := []*WealthsimpleAPI{
+		f.newClient(WithSessionStore(NewFileSessionStore(path))),
+		f.newClient(WithSessionStore(NewFileSessionStore(path))),
+	}
+	var wg sync.WaitGroup
+	for _, api := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := api.refreshAccessToken(ctx, "access-0"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := f.tokenRequests.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want 1", got)
+	}
+	for i, api := range clients {
+		if got := api.CurrentSession().RefreshToken; got != "refresh-1" {
+			t.Errorf("client %d holds %s, want refresh-1", i, got)
+		}
+	}
+}
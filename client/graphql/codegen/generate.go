@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"slices"
+	"strings"
+
+	"github.com/vpineda1996/wealthgo/client/graphql/validation"
+)
+
+// builtinScalars maps the GraphQL built-in scalars to Go types
+var builtinScalars = map[string]string{
+	"ID":      "string",
+	"String":  "string",
+	"Int":     "int64",
+	"Float":   "float64",
+	"Boolean": "bool",
+}
+
+// goField is a member of a generated struct
+type goField struct {
+	name string
+	key  string
+	typ  string
+}
+
+// goVariant is the member of an abstract type struct holding the fields specific to one possible type
+type goVariant struct {
+	typeName string
+	name     string
+	typ      string
+}
+
+// goType is a generated named type
+type goType struct {
+	name    string
+	doc     string
+	kind    validation.TypeKind
+	fields  []goField
+	variant []goVariant
+	// alias names an identical type generated first
+	alias     string
+	signature string
+}
+
+// fieldSet holds the fields collected from a selection set, fragments merged in, keyed by response name
+type fieldSet struct {
+	keys   []string
+	fields map[string][]*validation.Field
+	// conditional holds the fields only selected when an abstract type is the given possible type
+	conditional map[string]*fieldSet
+}
+
+func newFieldSet() *fieldSet {
+	return &fieldSet{fields: make(map[string][]*validation.Field), conditional: make(map[string]*fieldSet)}
+}
+
+func (s *fieldSet) add(field *validation.Field) {
+	key := field.Name
+	if field.Alias != "" {
+		key = field.Alias
+	}
+	if _, ok := s.fields[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.fields[key] = append(s.fields[key], field)
+}
+
+// generator builds the Go types of a set of documents
+type generator struct {
+	schema *validation.Schema
+	doc    *validation.Document
+	types  []*goType
+	// bySignature finds the type already generated for a selection, so identical ones are shared
+	bySignature map[string]*goType
+	// fragments maps fragment names to the signature of their type
+	fragments map[string]string
+	names     map[string]bool
+	reserved  map[string]bool
+	scalars   map[string]bool
+}
+
+// generate returns the formatted source of the types of every operation and fragment of docs
+func generate(schema *validation.Schema, docs []*validation.Document, pkg string) ([]byte, error) {
+	g := &generator{
+		schema:      schema,
+		bySignature: make(map[string]*goType),
+		fragments:   make(map[string]string),
+		names:       make(map[string]bool),
+		reserved:    make(map[string]bool),
+		scalars:     make(map[string]bool),
+	}
+
+	// Fragment and operation names are reserved so nested selections don't take them
+	for _, doc := range docs {
+		for name := range doc.Fragments {
+			g.reserved[name] = true
+		}
+		for _, operation := range doc.Operations {
+			g.reserved[operation.Name+"Response"] = true
+		}
+	}
+
+	for _, doc := range docs {
+		g.doc = doc
+		for _, name := range g.fragmentOrder() {
+			if err := g.fragment(doc.Fragments[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, doc := range docs {
+		g.doc = doc
+		for _, operation := range doc.Operations {
+			if err := g.operation(operation); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return g.render(pkg)
+}
+
+// fragmentOrder sorts the fragments of the current document so that every fragment comes after the ones
+// it spreads, the types of the spread fragments can then be reused
+func (g *generator) fragmentOrder() []string {
+	var order []string
+	visited := make(map[string]bool)
+	var visit func(name string)
+	var spreads func(selections []validation.Selection)
+	spreads = func(selections []validation.Selection) {
+		for _, selection := range selections {
+			switch s := selection.(type) {
+			case *validation.Field:
+				spreads(s.Selections)
+			case *validation.InlineFragment:
+				spreads(s.Selections)
+			case *validation.FragmentSpread:
+				visit(s.Name)
+			}
+		}
+	}
+	visit = func(name string) {
+		fragment, ok := g.doc.Fragments[name]
+		if !ok || visited[name] {
+			return
+		}
+		visited[name] = true
+		spreads(fragment.Selections)
+		order = append(order, name)
+	}
+
+	names := make([]string, 0, len(g.doc.Fragments))
+	for name := range g.doc.Fragments {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		visit(name)
+	}
+	return order
+}
+
+// fragment generates the type of a fragment, named after it
+func (g *generator) fragment(fragment *validation.Fragment) error {
+	parent := g.schema.Types[fragment.TypeCondition]
+	t, err := g.selectionType(parent, fragment.Selections, fragment.Name)
+	if err != nil {
+		return err
+	}
+
+	if signature, ok := g.fragments[fragment.Name]; ok {
+		if signature != t.signature {
+			return fmt.Errorf("fragment %s is defined more than once with different selections", fragment.Name)
+		}
+		return nil
+	}
+
+	t.doc = fmt.Sprintf("%s is the selection of fragment %s on %s", fragment.Name, fragment.Name, parent.Name)
+	g.register(t, fragment.Name, true)
+	g.fragments[fragment.Name] = t.signature
+	return nil
+}
+
+// operation generates the type of the data of an operation
+func (g *generator) operation(operation *validation.Operation) error {
+	if operation.Name == "" {
+		return fmt.Errorf("anonymous operations can't be generated")
+	}
+	root := map[string]string{
+		"query":        g.schema.QueryType,
+		"mutation":     g.schema.MutationType,
+		"subscription": g.schema.SubscriptionType,
+	}[operation.Kind]
+
+	t, err := g.selectionType(g.schema.Types[root], operation.Selections, operation.Name)
+	if err != nil {
+		return err
+	}
+	name := operation.Name + "Response"
+	t.doc = fmt.Sprintf("%s is the data of the %s %s", name, operation.Name, operation.Kind)
+	g.register(t, name, true)
+	return nil
+}
+
+// selectionType builds the type of a selection set on parent, nested types are named after hint
+func (g *generator) selectionType(parent *validation.TypeDefinition, selections []validation.Selection, hint string) (*goType, error) {
+	set := newFieldSet()
+	g.collect(parent, selections, set)
+	return g.setType(parent, set, hint)
+}
+
+// collect adds the fields of selections to set, merging fragments that apply to parent and keeping
+// the ones specific to a possible type of an abstract parent apart
+func (g *generator) collect(parent *validation.TypeDefinition, selections []validation.Selection, set *fieldSet) {
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *validation.Field:
+			set.add(s)
+		case *validation.FragmentSpread:
+			fragment := g.doc.Fragments[s.Name]
+			g.collectFragment(parent, fragment.TypeCondition, fragment.Selections, set)
+		case *validation.InlineFragment:
+			condition := s.TypeCondition
+			if condition == "" {
+				condition = parent.Name
+			}
+			g.collectFragment(parent, condition, s.Selections, set)
+		}
+	}
+}
+
+// collectFragment adds the selections of a fragment on condition to set
+func (g *generator) collectFragment(parent *validation.TypeDefinition, condition string, selections []validation.Selection, set *fieldSet) {
+	if condition == parent.Name || (parent.Kind == validation.KindObject && slices.Contains(g.schema.PossibleTypes(condition), parent.Name)) {
+		g.collect(parent, selections, set)
+		return
+	}
+	if parent.Kind == validation.KindObject {
+		// The fragment can never apply
+		return
+	}
+
+	possible := g.schema.PossibleTypes(parent.Name)
+	for _, typeName := range g.schema.PossibleTypes(condition) {
+		if !slices.Contains(possible, typeName) {
+			continue
+		}
+		conditional, ok := set.conditional[typeName]
+		if !ok {
+			conditional = newFieldSet()
+			set.conditional[typeName] = conditional
+		}
+		g.collect(g.schema.Types[typeName], selections, conditional)
+	}
+}
+
+// setType builds the type of the fields collected in set
+func (g *generator) setType(parent *validation.TypeDefinition, set *fieldSet, hint string) (*goType, error) {
+	t := &goType{kind: parent.Kind}
+	var signature strings.Builder
+	signature.WriteString(parent.Name + "{")
+
+	for _, key := range set.keys {
+		field, err := g.field(parent, key, set.fields[key], hint)
+		if err != nil {
+			return nil, err
+		}
+		t.fields = append(t.fields, field)
+		signature.WriteString(field.key + ":" + field.typ + ";")
+	}
+
+	if parent.Kind != validation.KindObject {
+		if !slices.Contains(set.keys, "__typename") {
+			return nil, fmt.Errorf("selection %s on %s %s must select __typename", hint, parent.Kind, parent.Name)
+		}
+
+		typeNames := make([]string, 0, len(set.conditional))
+		for typeName := range set.conditional {
+			typeNames = append(typeNames, typeName)
+		}
+		slices.Sort(typeNames)
+
+		for _, typeName := range typeNames {
+			variantHint := hint + goName(typeName)
+			variant, err := g.setType(g.schema.Types[typeName], set.conditional[typeName], variantHint)
+			if err != nil {
+				return nil, err
+			}
+			variant.doc = fmt.Sprintf("%s holds the fields selected when %s is a %s", variantHint, hint, typeName)
+			variantType := g.register(variant, variantHint, false)
+			t.variant = append(t.variant, goVariant{typeName: typeName, name: goName(typeName), typ: variantType})
+			signature.WriteString("|" + typeName + ":" + variantType)
+		}
+	}
+
+	signature.WriteString("}")
+	t.signature = signature.String()
+	return t, nil
+}
+
+// field builds the member of a struct holding the response key of parent
+func (g *generator) field(parent *validation.TypeDefinition, key string, fields []*validation.Field, hint string) (goField, error) {
+	name := fields[0].Name
+	for _, field := range fields[1:] {
+		if field.Name != name {
+			return goField{}, fmt.Errorf("%s selects both %s and %s as %s", hint, name, field.Name, key)
+		}
+	}
+	if name == "__typename" {
+		return goField{name: "Typename", key: key, typ: "string"}, nil
+	}
+
+	def, ok := parent.Fields[name]
+	if !ok {
+		return goField{}, fmt.Errorf("unknown field %s on type %s", name, parent.Name)
+	}
+	target := g.schema.Types[def.Type.NamedType()]
+
+	var base string
+	switch {
+	case validation.IsComposite(target):
+		var selections []validation.Selection
+		for _, field := range fields {
+			selections = append(selections, field.Selections...)
+		}
+		nestedHint := hint + goName(key)
+		nested, err := g.selectionType(target, selections, nestedHint)
+		if err != nil {
+			return goField{}, err
+		}
+		nested.doc = fmt.Sprintf("%s holds the %s selected by %s.%s", nestedHint, target.Name, hint, key)
+		base = g.register(nested, nestedHint, false)
+	case builtinScalars[target.Name] != "":
+		base = builtinScalars[target.Name]
+	default:
+		// Custom scalars and enums get a named string type
+		g.scalars[target.Name] = true
+		base = target.Name
+	}
+
+	return goField{name: goName(key), key: key, typ: goTypeOf(def.Type, base)}, nil
+}
+
+// register adds t under name unless an identical type exists, and returns the name to use for it.
+// Reserved names are only given out when reserved is set
+func (g *generator) register(t *goType, name string, reserved bool) string {
+	if existing, ok := g.bySignature[t.signature]; ok {
+		if !reserved {
+			return existing.name
+		}
+		// Fragments and operations always get their own name
+		t.alias = existing.name
+	}
+
+	if !reserved || g.names[name] {
+		base := name
+		for i := 2; g.names[name] || (g.reserved[name] && !reserved); i++ {
+			name = fmt.Sprintf("%s%d", base, i)
+		}
+	}
+	t.name = name
+	if t.alias == "" {
+		g.bySignature[t.signature] = t
+	}
+	g.names[name] = true
+	g.types = append(g.types, t)
+	return name
+}
+
+// goTypeOf wraps base into the slices and pointers matching ref, nullable values are pointers
+// except for lists, which are nil instead
+func goTypeOf(ref *validation.TypeRef, base string) string {
+	if ref.Elem != nil {
+		return "[]" + goTypeOf(ref.Elem, base)
+	}
+	if ref.NonNull {
+		return base
+	}
+	return "*" + base
+}
+
+// goName turns a GraphQL name into an exported Go identifier
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(strings.TrimLeft(name, "_"), "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// render writes the generated types as a formatted Go file
+func (g *generator) render(pkg string) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by go generate; DO NOT EDIT.\n")
+	b.WriteString("// This file was generated from the GraphQL queries\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+
+	needsJSON := slices.ContainsFunc(g.types, func(t *goType) bool { return len(t.variant) > 0 && t.alias == "" })
+	if needsJSON {
+		b.WriteString("import \"encoding/json\"\n\n")
+	}
+
+	scalars := make([]string, 0, len(g.scalars))
+	for name := range g.scalars {
+		scalars = append(scalars, name)
+	}
+	slices.Sort(scalars)
+	for _, name := range scalars {
+		def := g.schema.Types[name]
+		fmt.Fprintf(&b, "// %s is the %s %s\ntype %s string\n\n", name, name, def.Kind, name)
+		if def.Kind == validation.KindEnum {
+			values := make([]string, 0, len(def.EnumValues))
+			for value := range def.EnumValues {
+				values = append(values, value)
+			}
+			slices.Sort(values)
+			b.WriteString("const (\n")
+			for _, value := range values {
+				fmt.Fprintf(&b, "%s%s %s = %q\n", name, goName(strings.ToLower(value)), name, value)
+			}
+			b.WriteString(")\n\n")
+		}
+	}
+
+	for _, t := range g.types {
+		fmt.Fprintf(&b, "// %s\n", t.doc)
+		if t.alias != "" {
+			fmt.Fprintf(&b, "type %s = %s\n\n", t.name, t.alias)
+			continue
+		}
+
+		fmt.Fprintf(&b, "type %s struct {\n", t.name)
+		for _, field := range t.fields {
+			fmt.Fprintf(&b, "%s %s `json:%q`\n", field.name, field.typ, field.key)
+		}
+		if len(t.variant) > 0 {
+			b.WriteString("\n// Set according to __typename\n")
+			for _, variant := range t.variant {
+				fmt.Fprintf(&b, "%s *%s `json:\"-\"`\n", variant.name, variant.typ)
+			}
+		}
+		b.WriteString("}\n\n")
+
+		if len(t.variant) > 0 {
+			fmt.Fprintf(&b, "// UnmarshalJSON decodes the common fields, then the ones specific to the type named by __typename\n")
+			fmt.Fprintf(&b, "func (v *%s) UnmarshalJSON(data []byte) error {\n", t.name)
+			fmt.Fprintf(&b, "type common %s\n", t.name)
+			b.WriteString("if err := json.Unmarshal(data, (*common)(v)); err != nil {\nreturn err\n}\n")
+			b.WriteString("switch v.Typename {\n")
+			for _, variant := range t.variant {
+				fmt.Fprintf(&b, "case %q:\nv.%s = new(%s)\nreturn json.Unmarshal(data, v.%s)\n", variant.typeName, variant.name, variant.typ, variant.name)
+			}
+			b.WriteString("}\nreturn nil\n}\n\n")
+		}
+	}
+
+	source, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return source, nil
+}
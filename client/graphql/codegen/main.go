@@ -0,0 +1,80 @@
+// Command codegen generates Go types from the selection sets of the GraphQL operations and fragments of a
+// directory, after validating them against the schema. It is run through go generate:
+//
+//	go run ../codegen -schema ../schema -queries . -out queries.generated.go -package queries
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/vpineda1996/wealthgo/client/graphql/validation"
+)
+
+func main() {
+	schemaDir := flag.String("schema", "../schema", "directory holding the schema files")
+	queriesDir := flag.String("queries", ".", "directory holding the operation files")
+	out := flag.String("out", "queries.generated.go", "file to write")
+	pkg := flag.String("package", "queries", "package of the generated file")
+	flag.Parse()
+
+	if err := run(*schemaDir, *queriesDir, *out, *pkg); err != nil {
+		fmt.Fprintln(os.Stderr, "codegen:", err)
+		os.Exit(1)
+	}
+}
+
+// run validates the operations of queriesDir and writes their types to out
+func run(schemaDir, queriesDir, out, pkg string) error {
+	schema, err := validation.LoadSchema(os.DirFS(schemaDir), ".")
+	if err != nil {
+		return err
+	}
+	docs, err := loadDocuments(queriesDir)
+	if err != nil {
+		return err
+	}
+
+	errs := schema.Validate()
+	for _, doc := range docs {
+		errs = append(errs, validation.Validate(schema, doc)...)
+	}
+	if len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintln(os.Stderr, e)
+		}
+		return fmt.Errorf("%d problems found, fix them before generating", len(errs))
+	}
+
+	source, err := generate(schema, docs, pkg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, source, 0o644)
+}
+
+// loadDocuments parses every .graphql file of dir, in name order
+func loadDocuments(dir string) ([]*validation.Document, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.graphql"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+
+	var docs []*validation.Document
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := validation.ParseQuery(filepath.Base(file), string(content))
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
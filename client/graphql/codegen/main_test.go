@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGeneratedQueriesUpToDate fails when queries.generated.go differs from what the queries and schema produce,
+// run go generate ./client/graphql/queries to update it
+func TestGeneratedQueriesUpToDate(t *testing.T) {
+	const checkedIn = "../queries/queries.generated.go"
+
+	out := filepath.Join(t.TempDir(), "queries.generated.go")
+	if err := run("../schema", "../queries", out, "queries"); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(checkedIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date, run go generate ./client/graphql/queries", checkedIn)
+	}
+}
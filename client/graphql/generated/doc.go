@@ -1,3 +1,7 @@
+// Package generated holds models generated by graphql-codegen-go from the GraphQL schema.
+//
+// Deprecated: the models predate the current schema and the client no longer uses them, use the types of
+// the queries package, which the client methods return.
 package generated
 
 //go:generate graphql-codegen-go -config ./config.yaml
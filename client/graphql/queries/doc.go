@@ -0,0 +1,5 @@
+// Package queries holds the response types of the embedded GraphQL operations, generated from their
+// selection sets so that every field they request has a Go representation
+package queries
+
+//go:generate go run ../codegen -schema ../schema -queries . -out queries.generated.go -package queries
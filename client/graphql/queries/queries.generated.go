@@ -0,0 +1,685 @@
+// Code generated by go generate; DO NOT EDIT.
+// This file was generated from the GraphQL queries
+
+package queries
+
+import "encoding/json"
+
+// Date is the Date scalar
+type Date string
+
+// Balance is the selection of fragment Balance on Balance
+type Balance struct {
+	Quantity   string `json:"quantity"`
+	SecurityId string `json:"securityId"`
+	Typename   string `json:"__typename"`
+}
+
+// AccountWithBalanceCustodianAccountsFinancialsCustodianAccountFinancialsSo holds the fields selected when AccountWithBalanceCustodianAccountsFinancials is a CustodianAccountFinancialsSo
+type AccountWithBalanceCustodianAccountsFinancialsCustodianAccountFinancialsSo struct {
+	Balance  []Balance `json:"balance"`
+	Typename string    `json:"__typename"`
+}
+
+// AccountWithBalanceCustodianAccountsFinancials holds the CustodianAccountFinancials selected by AccountWithBalanceCustodianAccounts.financials
+type AccountWithBalanceCustodianAccountsFinancials struct {
+	Typename string `json:"__typename"`
+
+	// Set according to __typename
+	CustodianAccountFinancialsSo *AccountWithBalanceCustodianAccountsFinancialsCustodianAccountFinancialsSo `json:"-"`
+}
+
+// UnmarshalJSON decodes the common fields, then the ones specific to the type named by __typename
+func (v *AccountWithBalanceCustodianAccountsFinancials) UnmarshalJSON(data []byte) error {
+	type common AccountWithBalanceCustodianAccountsFinancials
+	if err := json.Unmarshal(data, (*common)(v)); err != nil {
+		return err
+	}
+	switch v.Typename {
+	case "CustodianAccountFinancialsSo":
+		v.CustodianAccountFinancialsSo = new(AccountWithBalanceCustodianAccountsFinancialsCustodianAccountFinancialsSo)
+		return json.Unmarshal(data, v.CustodianAccountFinancialsSo)
+	}
+	return nil
+}
+
+// AccountWithBalanceCustodianAccounts holds the CustodianAccount selected by AccountWithBalance.custodianAccounts
+type AccountWithBalanceCustodianAccounts struct {
+	Id         string                                         `json:"id"`
+	Financials *AccountWithBalanceCustodianAccountsFinancials `json:"financials"`
+	Typename   string                                         `json:"__typename"`
+}
+
+// AccountWithBalance is the selection of fragment AccountWithBalance on Account
+type AccountWithBalance struct {
+	Id                string                                `json:"id"`
+	CustodianAccounts []AccountWithBalanceCustodianAccounts `json:"custodianAccounts"`
+	Typename          string                                `json:"__typename"`
+}
+
+// Activity is the selection of fragment Activity on ActivityFeedItem
+type Activity struct {
+	AccountId                     *string `json:"accountId"`
+	AftOriginatorName             *string `json:"aftOriginatorName"`
+	AftTransactionCategory        *string `json:"aftTransactionCategory"`
+	AftTransactionType            *string `json:"aftTransactionType"`
+	Amount                        string  `json:"amount"`
+	AmountSign                    *string `json:"amountSign"`
+	AssetQuantity                 string  `json:"assetQuantity"`
+	AssetSymbol                   *string `json:"assetSymbol"`
+	CanonicalId                   *string `json:"canonicalId"`
+	Currency                      *string `json:"currency"`
+	ETransferEmail                *string `json:"eTransferEmail"`
+	ETransferName                 *string `json:"eTransferName"`
+	ExternalCanonicalId           *string `json:"externalCanonicalId"`
+	IdentityId                    *string `json:"identityId"`
+	InstitutionName               *string `json:"institutionName"`
+	OccurredAt                    *string `json:"occurredAt"`
+	P2pHandle                     *string `json:"p2pHandle"`
+	P2pMessage                    *string `json:"p2pMessage"`
+	SpendMerchant                 *string `json:"spendMerchant"`
+	SecurityId                    *string `json:"securityId"`
+	BillPayCompanyName            *string `json:"billPayCompanyName"`
+	BillPayPayeeNickname          *string `json:"billPayPayeeNickname"`
+	RedactedExternalAccountNumber *string `json:"redactedExternalAccountNumber"`
+	OpposingAccountId             *string `json:"opposingAccountId"`
+	Status                        string  `json:"status"`
+	SubType                       string  `json:"subType"`
+	Type                          string  `json:"type"`
+	StrikePrice                   string  `json:"strikePrice"`
+	ContractType                  *string `json:"contractType"`
+	ExpiryDate                    *string `json:"expiryDate"`
+	ChequeNumber                  *string `json:"chequeNumber"`
+	ProvisionalCreditAmount       string  `json:"provisionalCreditAmount"`
+	PrimaryBlocker                *string `json:"primaryBlocker"`
+	InterestRate                  string  `json:"interestRate"`
+	Frequency                     *string `json:"frequency"`
+	CounterAssetSymbol            *string `json:"counterAssetSymbol"`
+	RewardProgram                 *string `json:"rewardProgram"`
+	CounterPartyCurrency          *string `json:"counterPartyCurrency"`
+	CounterPartyCurrencyAmount    string  `json:"counterPartyCurrencyAmount"`
+	CounterPartyName              *string `json:"counterPartyName"`
+	FxRate                        string  `json:"fxRate"`
+	Fees                          string  `json:"fees"`
+	Reference                     *string `json:"reference"`
+	Typename                      string  `json:"__typename"`
+}
+
+// AccountFeature is the selection of fragment AccountFeature on AccountFeature
+type AccountFeature struct {
+	Name     string `json:"name"`
+	Enabled  bool   `json:"enabled"`
+	Typename string `json:"__typename"`
+}
+
+// AccountOwnerInvitation is the selection of fragment AccountOwnerInvitation on AccountOwnerInvitation
+type AccountOwnerInvitation struct {
+	Id           string  `json:"id"`
+	CreatedAt    *Date   `json:"createdAt"`
+	InviteeName  *string `json:"inviteeName"`
+	InviteeEmail *string `json:"inviteeEmail"`
+	InviterName  *string `json:"inviterName"`
+	InviterEmail *string `json:"inviterEmail"`
+	UpdatedAt    *Date   `json:"updatedAt"`
+	SentAt       *Date   `json:"sentAt"`
+	Status       *string `json:"status"`
+	Typename     string  `json:"__typename"`
+}
+
+// AccountOwner is the selection of fragment AccountOwner on AccountOwner
+type AccountOwner struct {
+	AccountId                      string                   `json:"accountId"`
+	IdentityId                     string                   `json:"identityId"`
+	AccountNickname                *string                  `json:"accountNickname"`
+	ClientCanonicalId              *string                  `json:"clientCanonicalId"`
+	AccountOpeningAgreementsSigned *bool                    `json:"accountOpeningAgreementsSigned"`
+	Name                           *string                  `json:"name"`
+	Email                          *string                  `json:"email"`
+	OwnershipType                  *string                  `json:"ownershipType"`
+	ActiveInvitation               *AccountOwnerInvitation  `json:"activeInvitation"`
+	SentInvitations                []AccountOwnerInvitation `json:"sentInvitations"`
+	Typename                       string                   `json:"__typename"`
+}
+
+// AccountCore is the selection of fragment AccountCore on Account
+type AccountCore struct {
+	Id                           string           `json:"id"`
+	ArchivedAt                   *Date            `json:"archivedAt"`
+	Branch                       *string          `json:"branch"`
+	ClosedAt                     *Date            `json:"closedAt"`
+	CreatedAt                    Date             `json:"createdAt"`
+	CacheExpiredAt               *Date            `json:"cacheExpiredAt"`
+	Currency                     *string          `json:"currency"`
+	RequiredIdentityVerification *string          `json:"requiredIdentityVerification"`
+	UnifiedAccountType           *string          `json:"unifiedAccountType"`
+	SupportedCurrencies          []string         `json:"supportedCurrencies"`
+	Nickname                     *string          `json:"nickname"`
+	Status                       string           `json:"status"`
+	AccountOwnerConfiguration    *string          `json:"accountOwnerConfiguration"`
+	AccountFeatures              []AccountFeature `json:"accountFeatures"`
+	AccountOwners                []AccountOwner   `json:"accountOwners"`
+	Type                         *string          `json:"type"`
+	Typename                     string           `json:"__typename"`
+}
+
+// CustodianAccount is the selection of fragment CustodianAccount on CustodianAccount
+type CustodianAccount struct {
+	Id        string  `json:"id"`
+	Branch    *string `json:"branch"`
+	Custodian *string `json:"custodian"`
+	Status    string  `json:"status"`
+	UpdatedAt *Date   `json:"updatedAt"`
+	Typename  string  `json:"__typename"`
+}
+
+// Account is the selection of fragment Account on Account
+type Account struct {
+	Id                           string             `json:"id"`
+	ArchivedAt                   *Date              `json:"archivedAt"`
+	Branch                       *string            `json:"branch"`
+	ClosedAt                     *Date              `json:"closedAt"`
+	CreatedAt                    Date               `json:"createdAt"`
+	CacheExpiredAt               *Date              `json:"cacheExpiredAt"`
+	Currency                     *string            `json:"currency"`
+	RequiredIdentityVerification *string            `json:"requiredIdentityVerification"`
+	UnifiedAccountType           *string            `json:"unifiedAccountType"`
+	SupportedCurrencies          []string           `json:"supportedCurrencies"`
+	Nickname                     *string            `json:"nickname"`
+	Status                       string             `json:"status"`
+	AccountOwnerConfiguration    *string            `json:"accountOwnerConfiguration"`
+	AccountFeatures              []AccountFeature   `json:"accountFeatures"`
+	AccountOwners                []AccountOwner     `json:"accountOwners"`
+	Type                         *string            `json:"type"`
+	Typename                     string             `json:"__typename"`
+	CustodianAccounts            []CustodianAccount `json:"custodianAccounts"`
+}
+
+// Money is the selection of fragment Money on Money
+type Money struct {
+	Amount   string `json:"amount"`
+	Cents    int64  `json:"cents"`
+	Currency string `json:"currency"`
+	Typename string `json:"__typename"`
+}
+
+// SimpleReturns is the selection of fragment SimpleReturns on SimpleReturns
+type SimpleReturns struct {
+	Amount        Money  `json:"amount"`
+	AsOf          *Date  `json:"asOf"`
+	Rate          string `json:"rate"`
+	ReferenceDate *Date  `json:"referenceDate"`
+	Typename      string `json:"__typename"`
+}
+
+// AccountCurrentFinancials is the selection of fragment AccountCurrentFinancials on AccountCurrentFinancials
+type AccountCurrentFinancials struct {
+	Id                    string         `json:"id"`
+	NetLiquidationValueV2 *Money         `json:"netLiquidationValueV2"`
+	NetDeposits           *Money         `json:"netDeposits"`
+	SimpleReturns         *SimpleReturns `json:"simpleReturns"`
+	TotalDeposits         *Money         `json:"totalDeposits"`
+	TotalWithdrawals      *Money         `json:"totalWithdrawals"`
+	Typename              string         `json:"__typename"`
+}
+
+// CustodianAccountCurrentFinancialValues is the selection of fragment CustodianAccountCurrentFinancialValues on CustodianAccountCurrentFinancialValues
+type CustodianAccountCurrentFinancialValues struct {
+	Deposits            Money  `json:"deposits"`
+	Earnings            Money  `json:"earnings"`
+	NetDeposits         Money  `json:"netDeposits"`
+	NetLiquidationValue Money  `json:"netLiquidationValue"`
+	Withdrawals         Money  `json:"withdrawals"`
+	Typename            string `json:"__typename"`
+}
+
+// AccountFinancialsCustodianAccountsFinancials holds the CustodianAccountFinancials selected by AccountFinancialsCustodianAccounts.financials
+type AccountFinancialsCustodianAccountsFinancials struct {
+	Current  *CustodianAccountCurrentFinancialValues `json:"current"`
+	Typename string                                  `json:"__typename"`
+}
+
+// AccountFinancialsCustodianAccounts holds the CustodianAccount selected by AccountFinancials.custodianAccounts
+type AccountFinancialsCustodianAccounts struct {
+	Id         string                                        `json:"id"`
+	Branch     *string                                       `json:"branch"`
+	Financials *AccountFinancialsCustodianAccountsFinancials `json:"financials"`
+	Typename   string                                        `json:"__typename"`
+}
+
+// AccountFinancialsFinancials holds the AccountFinancials selected by AccountFinancials.financials
+type AccountFinancialsFinancials struct {
+	CurrentCombined *AccountCurrentFinancials `json:"currentCombined"`
+	Typename        string                    `json:"__typename"`
+}
+
+// AccountFinancials is the selection of fragment AccountFinancials on Account
+type AccountFinancials struct {
+	Id                string                               `json:"id"`
+	CustodianAccounts []AccountFinancialsCustodianAccounts `json:"custodianAccounts"`
+	Financials        AccountFinancialsFinancials          `json:"financials"`
+	Typename          string                               `json:"__typename"`
+}
+
+// AccountWithLink is the selection of fragment AccountWithLink on Account
+type AccountWithLink struct {
+	Id                           string             `json:"id"`
+	ArchivedAt                   *Date              `json:"archivedAt"`
+	Branch                       *string            `json:"branch"`
+	ClosedAt                     *Date              `json:"closedAt"`
+	CreatedAt                    Date               `json:"createdAt"`
+	CacheExpiredAt               *Date              `json:"cacheExpiredAt"`
+	Currency                     *string            `json:"currency"`
+	RequiredIdentityVerification *string            `json:"requiredIdentityVerification"`
+	UnifiedAccountType           *string            `json:"unifiedAccountType"`
+	SupportedCurrencies          []string           `json:"supportedCurrencies"`
+	Nickname                     *string            `json:"nickname"`
+	Status                       string             `json:"status"`
+	AccountOwnerConfiguration    *string            `json:"accountOwnerConfiguration"`
+	AccountFeatures              []AccountFeature   `json:"accountFeatures"`
+	AccountOwners                []AccountOwner     `json:"accountOwners"`
+	Type                         *string            `json:"type"`
+	Typename                     string             `json:"__typename"`
+	CustodianAccounts            []CustodianAccount `json:"custodianAccounts"`
+	LinkedAccount                *Account           `json:"linkedAccount"`
+}
+
+// AccountWithFinancialsCustodianAccounts holds the CustodianAccount selected by AccountWithFinancials.custodianAccounts
+type AccountWithFinancialsCustodianAccounts struct {
+	Id         string                                        `json:"id"`
+	Branch     *string                                       `json:"branch"`
+	Custodian  *string                                       `json:"custodian"`
+	Status     string                                        `json:"status"`
+	UpdatedAt  *Date                                         `json:"updatedAt"`
+	Typename   string                                        `json:"__typename"`
+	Financials *AccountFinancialsCustodianAccountsFinancials `json:"financials"`
+}
+
+// AccountWithFinancials is the selection of fragment AccountWithFinancials on Account
+type AccountWithFinancials struct {
+	Id                           string                                   `json:"id"`
+	ArchivedAt                   *Date                                    `json:"archivedAt"`
+	Branch                       *string                                  `json:"branch"`
+	ClosedAt                     *Date                                    `json:"closedAt"`
+	CreatedAt                    Date                                     `json:"createdAt"`
+	CacheExpiredAt               *Date                                    `json:"cacheExpiredAt"`
+	Currency                     *string                                  `json:"currency"`
+	RequiredIdentityVerification *string                                  `json:"requiredIdentityVerification"`
+	UnifiedAccountType           *string                                  `json:"unifiedAccountType"`
+	SupportedCurrencies          []string                                 `json:"supportedCurrencies"`
+	Nickname                     *string                                  `json:"nickname"`
+	Status                       string                                   `json:"status"`
+	AccountOwnerConfiguration    *string                                  `json:"accountOwnerConfiguration"`
+	AccountFeatures              []AccountFeature                         `json:"accountFeatures"`
+	AccountOwners                []AccountOwner                           `json:"accountOwners"`
+	Type                         *string                                  `json:"type"`
+	Typename                     string                                   `json:"__typename"`
+	CustodianAccounts            []AccountWithFinancialsCustodianAccounts `json:"custodianAccounts"`
+	LinkedAccount                *Account                                 `json:"linkedAccount"`
+	Financials                   AccountFinancialsFinancials              `json:"financials"`
+}
+
+// AllAccountFinancialsAccountsPageInfo holds the PageInfo selected by AllAccountFinancialsAccounts.pageInfo
+type AllAccountFinancialsAccountsPageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor"`
+	Typename    string  `json:"__typename"`
+}
+
+// AllAccountFinancialsAccountsEdges holds the AccountEdge selected by AllAccountFinancialsAccounts.edges
+type AllAccountFinancialsAccountsEdges struct {
+	Cursor   string                `json:"cursor"`
+	Node     AccountWithFinancials `json:"node"`
+	Typename string                `json:"__typename"`
+}
+
+// AllAccountFinancialsAccounts holds the AccountConnection selected by AllAccountFinancials.accounts
+type AllAccountFinancialsAccounts struct {
+	PageInfo AllAccountFinancialsAccountsPageInfo `json:"pageInfo"`
+	Edges    []AllAccountFinancialsAccountsEdges  `json:"edges"`
+	Typename string                               `json:"__typename"`
+}
+
+// AllAccountFinancials is the selection of fragment AllAccountFinancials on Identity
+type AllAccountFinancials struct {
+	Accounts *AllAccountFinancialsAccounts `json:"accounts"`
+	Typename string                        `json:"__typename"`
+}
+
+// BankVerificationDocument is the selection of fragment BankVerificationDocument on VerificationDocument
+type BankVerificationDocument struct {
+	Id           string  `json:"id"`
+	Acceptable   bool    `json:"acceptable"`
+	UpdatedAt    string  `json:"updatedAt"`
+	CreatedAt    string  `json:"createdAt"`
+	DocumentId   string  `json:"documentId"`
+	DocumentType string  `json:"documentType"`
+	RejectReason *string `json:"rejectReason"`
+	ReviewedAt   *string `json:"reviewedAt"`
+	ReviewedBy   *string `json:"reviewedBy"`
+	Typename     string  `json:"__typename"`
+}
+
+// BankAccountVerification is the selection of fragment BankAccountVerification on BankAccountVerification
+type BankAccountVerification struct {
+	CustodianProcessedAt *string                   `json:"custodianProcessedAt"`
+	CustodianStatus      *string                   `json:"custodianStatus"`
+	Document             *BankVerificationDocument `json:"document"`
+	Typename             string                    `json:"__typename"`
+}
+
+// CaBankAccount is the selection of fragment CaBankAccount on CaBankAccount
+type CaBankAccount struct {
+	AccountName   string `json:"accountName"`
+	AccountNumber string `json:"accountNumber"`
+	Typename      string `json:"__typename"`
+}
+
+// UsBankAccount is the selection of fragment UsBankAccount on UsBankAccount
+type UsBankAccount struct {
+	AccountName   string `json:"accountName"`
+	AccountNumber string `json:"accountNumber"`
+	Typename      string `json:"__typename"`
+}
+
+// BankAccount is the selection of fragment BankAccount on BankAccount
+type BankAccount struct {
+	Id                    string                     `json:"id"`
+	AccountName           string                     `json:"accountName"`
+	Corporate             bool                       `json:"corporate"`
+	CreatedAt             string                     `json:"createdAt"`
+	Currency              string                     `json:"currency"`
+	InstitutionName       string                     `json:"institutionName"`
+	Jurisdiction          string                     `json:"jurisdiction"`
+	Nickname              *string                    `json:"nickname"`
+	Type                  string                     `json:"type"`
+	UpdatedAt             string                     `json:"updatedAt"`
+	VerificationDocuments []BankVerificationDocument `json:"verificationDocuments"`
+	Verifications         []BankAccountVerification  `json:"verifications"`
+	Typename              string                     `json:"__typename"`
+
+	// Set according to __typename
+	CaBankAccount *CaBankAccount `json:"-"`
+	UsBankAccount *UsBankAccount `json:"-"`
+}
+
+// UnmarshalJSON decodes the common fields, then the ones specific to the type named by __typename
+func (v *BankAccount) UnmarshalJSON(data []byte) error {
+	type common BankAccount
+	if err := json.Unmarshal(data, (*common)(v)); err != nil {
+		return err
+	}
+	switch v.Typename {
+	case "CaBankAccount":
+		v.CaBankAccount = new(CaBankAccount)
+		return json.Unmarshal(data, v.CaBankAccount)
+	case "UsBankAccount":
+		v.UsBankAccount = new(UsBankAccount)
+		return json.Unmarshal(data, v.UsBankAccount)
+	}
+	return nil
+}
+
+// BankAccountOwner is the selection of fragment BankAccountOwner on BankAccountOwner
+type BankAccountOwner struct {
+	BankAccount *BankAccount `json:"bankAccount"`
+	Typename    string       `json:"__typename"`
+}
+
+// FundsTransferSchedule holds the Schedule selected by FundsTransfer.schedule
+type FundsTransferSchedule struct {
+	Id       string `json:"id"`
+	Typename string `json:"__typename"`
+}
+
+// FundsTransfer is the selection of fragment FundsTransfer on FundsTransfer
+type FundsTransfer struct {
+	Id           string                 `json:"id"`
+	Status       string                 `json:"status"`
+	Cancellable  bool                   `json:"cancellable"`
+	RejectReason *string                `json:"rejectReason"`
+	Schedule     *FundsTransferSchedule `json:"schedule"`
+	Source       *BankAccountOwner      `json:"source"`
+	Destination  *BankAccountOwner      `json:"destination"`
+	Typename     string                 `json:"__typename"`
+}
+
+// InstitutionalTransferTimelineExpectation holds the TimelineExpectation selected by InstitutionalTransfer.timelineExpectation
+type InstitutionalTransferTimelineExpectation struct {
+	LowerBound string `json:"lowerBound"`
+	UpperBound string `json:"upperBound"`
+	Typename   string `json:"__typename"`
+}
+
+// InstitutionalTransferParentInstitution holds the ParentInstitution selected by InstitutionalTransfer.parentInstitution
+type InstitutionalTransferParentInstitution struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Typename string `json:"__typename"`
+}
+
+// InstitutionalTransferStateHistories holds the StateHistory selected by InstitutionalTransfer.stateHistories
+type InstitutionalTransferStateHistories struct {
+	Id                    string  `json:"id"`
+	State                 string  `json:"state"`
+	Notes                 *string `json:"notes"`
+	TransitionSubmittedBy *string `json:"transitionSubmittedBy"`
+	TransitionedAt        string  `json:"transitionedAt"`
+	TransitionCode        *string `json:"transitionCode"`
+	Typename              string  `json:"__typename"`
+}
+
+// InstitutionalTransferTransferFeeReimbursement holds the TransferFeeReimbursement selected by InstitutionalTransfer.transferFeeReimbursement
+type InstitutionalTransferTransferFeeReimbursement struct {
+	Id        string  `json:"id"`
+	FeeAmount float64 `json:"feeAmount"`
+	Typename  string  `json:"__typename"`
+}
+
+// InstitutionalTransfer is the selection of fragment InstitutionalTransfer on InstitutionalTransfer
+type InstitutionalTransfer struct {
+	Id                               string                                         `json:"id"`
+	AccountId                        string                                         `json:"accountId"`
+	State                            string                                         `json:"state"`
+	DocumentId                       *string                                        `json:"documentId"`
+	DocumentType                     *string                                        `json:"documentType"`
+	ExpectedCompletionDate           *string                                        `json:"expectedCompletionDate"`
+	TimelineExpectation              *InstitutionalTransferTimelineExpectation      `json:"timelineExpectation"`
+	EstimatedCompletionMaximum       *string                                        `json:"estimatedCompletionMaximum"`
+	EstimatedCompletionMinimum       *string                                        `json:"estimatedCompletionMinimum"`
+	InstitutionName                  string                                         `json:"institutionName"`
+	TransferStatus                   *string                                        `json:"transferStatus"`
+	RedactedInstitutionAccountNumber *string                                        `json:"redactedInstitutionAccountNumber"`
+	ExpectedValue                    *float64                                       `json:"expectedValue"`
+	TransferType                     string                                         `json:"transferType"`
+	Cancellable                      bool                                           `json:"cancellable"`
+	PdfUrl                           *string                                        `json:"pdfUrl"`
+	ClientVisibleState               *string                                        `json:"clientVisibleState"`
+	ShortStatusDescription           *string                                        `json:"shortStatusDescription"`
+	LongStatusDescription            *string                                        `json:"longStatusDescription"`
+	ProgressPercentage               *float64                                       `json:"progressPercentage"`
+	Type                             string                                         `json:"type"`
+	RolloverType                     *string                                        `json:"rolloverType"`
+	AutoSignatureEligible            *bool                                          `json:"autoSignatureEligible"`
+	ParentInstitution                *InstitutionalTransferParentInstitution        `json:"parentInstitution"`
+	StateHistories                   []InstitutionalTransferStateHistories          `json:"stateHistories"`
+	TransferFeeReimbursement         *InstitutionalTransferTransferFeeReimbursement `json:"transferFeeReimbursement"`
+	DocusignSentViaEmail             *bool                                          `json:"docusignSentViaEmail"`
+	ClientAccountType                *string                                        `json:"clientAccountType"`
+	PrimaryClientIdentityId          *string                                        `json:"primaryClientIdentityId"`
+	PrimaryOwnerSigned               *bool                                          `json:"primaryOwnerSigned"`
+	SecondaryOwnerSigned             *bool                                          `json:"secondaryOwnerSigned"`
+	Typename                         string                                         `json:"__typename"`
+}
+
+// HistoricalQuote is the selection of fragment HistoricalQuote on HistoricalQuote
+type HistoricalQuote struct {
+	AdjustedPrice *string `json:"adjustedPrice"`
+	Currency      *string `json:"currency"`
+	Date          *Date   `json:"date"`
+	SecurityId    *string `json:"securityId"`
+	Time          *string `json:"time"`
+	Typename      string  `json:"__typename"`
+}
+
+// MarginRates is the selection of fragment MarginRates on MarginRates
+type MarginRates struct {
+	ClientMarginRate float64 `json:"clientMarginRate"`
+	Typename         string  `json:"__typename"`
+}
+
+// SecurityMarketDataFundamentals holds the Fundamentals selected by SecurityMarketData.fundamentals
+type SecurityMarketDataFundamentals struct {
+	AvgVolume   float64 `json:"avgVolume"`
+	High52Week  float64 `json:"high52Week"`
+	Low52Week   float64 `json:"low52Week"`
+	Yield       float64 `json:"yield"`
+	PeRatio     float64 `json:"peRatio"`
+	MarketCap   float64 `json:"marketCap"`
+	Currency    string  `json:"currency"`
+	Description *string `json:"description"`
+	Typename    string  `json:"__typename"`
+}
+
+// SecurityMarketDataQuote holds the Quote selected by SecurityMarketData.quote
+type SecurityMarketDataQuote struct {
+	Bid           string `json:"bid"`
+	Ask           string `json:"ask"`
+	Open          string `json:"open"`
+	High          string `json:"high"`
+	Low           string `json:"low"`
+	Volume        int64  `json:"volume"`
+	AskSize       int64  `json:"askSize"`
+	BidSize       int64  `json:"bidSize"`
+	Last          string `json:"last"`
+	LastSize      int64  `json:"lastSize"`
+	QuotedAsOf    *Date  `json:"quotedAsOf"`
+	QuoteDate     *Date  `json:"quoteDate"`
+	Amount        string `json:"amount"`
+	PreviousClose string `json:"previousClose"`
+	Typename      string `json:"__typename"`
+}
+
+// SecurityMarketDataStock holds the Stock selected by SecurityMarketData.stock
+type SecurityMarketDataStock struct {
+	PrimaryExchange *string `json:"primaryExchange"`
+	PrimaryMic      *string `json:"primaryMic"`
+	Name            *string `json:"name"`
+	Symbol          string  `json:"symbol"`
+	Typename        string  `json:"__typename"`
+}
+
+// SecurityMarketData is the selection of fragment SecurityMarketData on Security
+type SecurityMarketData struct {
+	Id                   string                          `json:"id"`
+	AllowedOrderSubtypes []string                        `json:"allowedOrderSubtypes"`
+	MarginRates          *MarginRates                    `json:"marginRates"`
+	Fundamentals         *SecurityMarketDataFundamentals `json:"fundamentals"`
+	Quote                *SecurityMarketDataQuote        `json:"quote"`
+	Stock                *SecurityMarketDataStock        `json:"stock"`
+	Typename             string                          `json:"__typename"`
+}
+
+// SecuritySearchResultStock holds the Stock selected by SecuritySearchResult.stock
+type SecuritySearchResultStock struct {
+	Symbol          string  `json:"symbol"`
+	Name            *string `json:"name"`
+	PrimaryExchange *string `json:"primaryExchange"`
+	Typename        string  `json:"__typename"`
+}
+
+// SecuritySearchResultSecurityGroups holds the SecurityGroup selected by SecuritySearchResult.securityGroups
+type SecuritySearchResultSecurityGroups struct {
+	Id       string  `json:"id"`
+	Name     *string `json:"name"`
+	Typename string  `json:"__typename"`
+}
+
+// SecuritySearchResultQuoteV2 holds the EquityQuote selected by SecuritySearchResult.quoteV2
+type SecuritySearchResultQuoteV2 struct {
+	MarketStatus *string `json:"marketStatus"`
+	Typename     string  `json:"__typename"`
+}
+
+// SecuritySearchResult is the selection of fragment SecuritySearchResult on Security
+type SecuritySearchResult struct {
+	Id             string                               `json:"id"`
+	Buyable        *bool                                `json:"buyable"`
+	Status         *string                              `json:"status"`
+	Stock          *SecuritySearchResultStock           `json:"stock"`
+	SecurityGroups []SecuritySearchResultSecurityGroups `json:"securityGroups"`
+	QuoteV2        *SecuritySearchResultQuoteV2         `json:"quoteV2"`
+	Typename       string                               `json:"__typename"`
+}
+
+// FetchAccountsWithBalanceResponse is the data of the FetchAccountsWithBalance query
+type FetchAccountsWithBalanceResponse struct {
+	Accounts []AccountWithBalance `json:"accounts"`
+}
+
+// FetchActivityFeedItemsActivityFeedItemsEdges holds the ActivityFeedItemEdge selected by FetchActivityFeedItemsActivityFeedItems.edges
+type FetchActivityFeedItemsActivityFeedItemsEdges struct {
+	Node     Activity `json:"node"`
+	Typename string   `json:"__typename"`
+}
+
+// FetchActivityFeedItemsActivityFeedItems holds the ActivityFeedItemConnection selected by FetchActivityFeedItems.activityFeedItems
+type FetchActivityFeedItemsActivityFeedItems struct {
+	Edges    []FetchActivityFeedItemsActivityFeedItemsEdges `json:"edges"`
+	PageInfo AllAccountFinancialsAccountsPageInfo           `json:"pageInfo"`
+	Typename string                                         `json:"__typename"`
+}
+
+// FetchActivityFeedItemsResponse is the data of the FetchActivityFeedItems query
+type FetchActivityFeedItemsResponse struct {
+	ActivityFeedItems *FetchActivityFeedItemsActivityFeedItems `json:"activityFeedItems"`
+}
+
+// FetchAllAccountFinancialsIdentity holds the Identity selected by FetchAllAccountFinancials.identity
+type FetchAllAccountFinancialsIdentity struct {
+	Id       string                        `json:"id"`
+	Accounts *AllAccountFinancialsAccounts `json:"accounts"`
+	Typename string                        `json:"__typename"`
+}
+
+// FetchAllAccountFinancialsResponse is the data of the FetchAllAccountFinancials query
+type FetchAllAccountFinancialsResponse struct {
+	Identity *FetchAllAccountFinancialsIdentity `json:"identity"`
+}
+
+// FetchFundsTransferResponse is the data of the FetchFundsTransfer query
+type FetchFundsTransferResponse struct {
+	FundsTransfer *FundsTransfer `json:"fundsTransfer"`
+}
+
+// FetchInstitutionalTransferResponse is the data of the FetchInstitutionalTransfer query
+type FetchInstitutionalTransferResponse struct {
+	AccountTransfer *InstitutionalTransfer `json:"accountTransfer"`
+}
+
+// FetchSecurityHistoricalQuotesSecurity holds the Security selected by FetchSecurityHistoricalQuotes.security
+type FetchSecurityHistoricalQuotesSecurity struct {
+	Id               string            `json:"id"`
+	HistoricalQuotes []HistoricalQuote `json:"historicalQuotes"`
+	Typename         string            `json:"__typename"`
+}
+
+// FetchSecurityHistoricalQuotesResponse is the data of the FetchSecurityHistoricalQuotes query
+type FetchSecurityHistoricalQuotesResponse struct {
+	Security *FetchSecurityHistoricalQuotesSecurity `json:"security"`
+}
+
+// FetchSecurityMarketDataResponse is the data of the FetchSecurityMarketData query
+type FetchSecurityMarketDataResponse struct {
+	Security *SecurityMarketData `json:"security"`
+}
+
+// FetchSecuritySearchResultSecuritySearch holds the SecuritySearchResult selected by FetchSecuritySearchResult.securitySearch
+type FetchSecuritySearchResultSecuritySearch struct {
+	Results  []SecuritySearchResult `json:"results"`
+	Typename string                 `json:"__typename"`
+}
+
+// FetchSecuritySearchResultResponse is the data of the FetchSecuritySearchResult query
+type FetchSecuritySearchResultResponse struct {
+	SecuritySearch *FetchSecuritySearchResultSecuritySearch `json:"securitySearch"`
+}
@@ -0,0 +1,201 @@
+package validation
+
+import "fmt"
+
+// Position locates a token within a source file
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// TypeKind is the kind of a named schema type
+type TypeKind int
+
+const (
+	KindScalar TypeKind = iota
+	KindObject
+	KindInterface
+	KindUnion
+	KindEnum
+	KindInputObject
+)
+
+func (k TypeKind) String() string {
+	return [...]string{"scalar", "type", "interface", "union", "enum", "input"}[k]
+}
+
+// TypeRef is a reference to a type, possibly wrapped in lists and non-null markers
+type TypeRef struct {
+	// Name is set for named types, Elem for list types
+	Name    string
+	Elem    *TypeRef
+	NonNull bool
+}
+
+// NamedType returns the name of the innermost type
+func (t *TypeRef) NamedType() string {
+	if t.Elem != nil {
+		return t.Elem.NamedType()
+	}
+	return t.Name
+}
+
+func (t *TypeRef) String() string {
+	s := t.Name
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+// InputValue is an argument of a field or a field of an input object
+type InputValue struct {
+	Name         string
+	Type         *TypeRef
+	DefaultValue *Value
+	Pos          Position
+}
+
+// FieldDefinition is a field declared by an object or interface type
+type FieldDefinition struct {
+	Name      string
+	Arguments map[string]*InputValue
+	Type      *TypeRef
+	Pos       Position
+}
+
+// TypeDefinition is a named type declared by the schema
+type TypeDefinition struct {
+	Kind TypeKind
+	Name string
+	// Fields of object and interface types
+	Fields map[string]*FieldDefinition
+	// InputFields of input object types
+	InputFields map[string]*InputValue
+	// Interfaces implemented by object and interface types
+	Interfaces []string
+	// Members of union types
+	Members []string
+	// EnumValues of enum types
+	EnumValues map[string]bool
+	Pos        Position
+}
+
+// Schema holds every type declared by a set of schema files
+type Schema struct {
+	Types map[string]*TypeDefinition
+	// Root operation types, "Query", "Mutation" and "Subscription" unless a schema definition overrides them
+	QueryType        string
+	MutationType     string
+	SubscriptionType string
+}
+
+// ValueKind is the kind of a literal value or variable reference
+type ValueKind int
+
+const (
+	ValueVariable ValueKind = iota
+	ValueInt
+	ValueFloat
+	ValueString
+	ValueBoolean
+	ValueNull
+	ValueEnum
+	ValueList
+	ValueObject
+)
+
+// Value is a literal or a variable reference found in a document
+type Value struct {
+	Kind ValueKind
+	// Raw is the variable name or the literal as written
+	Raw    string
+	List   []*Value
+	Fields []*ObjectField
+	Pos    Position
+}
+
+// ObjectField is a member of an input object literal
+type ObjectField struct {
+	Name  string
+	Value *Value
+	Pos   Position
+}
+
+// Argument is an argument passed to a field
+type Argument struct {
+	Name  string
+	Value *Value
+	Pos   Position
+}
+
+// Selection is one of *Field, *FragmentSpread or *InlineFragment
+type Selection interface {
+	position() Position
+}
+
+// Field selects a field of the parent type
+type Field struct {
+	Alias      string
+	Name       string
+	Arguments  []*Argument
+	Selections []Selection
+	Pos        Position
+}
+
+// FragmentSpread includes a named fragment
+type FragmentSpread struct {
+	Name string
+	Pos  Position
+}
+
+// InlineFragment selects fields conditionally on the runtime type, TypeCondition may be empty
+type InlineFragment struct {
+	TypeCondition string
+	Selections    []Selection
+	Pos           Position
+}
+
+func (f *Field) position() Position          { return f.Pos }
+func (f *FragmentSpread) position() Position { return f.Pos }
+func (f *InlineFragment) position() Position { return f.Pos }
+
+// VariableDefinition declares a variable of an operation
+type VariableDefinition struct {
+	Name         string
+	Type         *TypeRef
+	DefaultValue *Value
+	Pos          Position
+}
+
+// Operation is a query, mutation or subscription
+type Operation struct {
+	// Kind is "query", "mutation" or "subscription"
+	Kind       string
+	Name       string
+	Variables  []*VariableDefinition
+	Selections []Selection
+	Pos        Position
+}
+
+// Fragment is a named fragment definition
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []Selection
+	Pos           Position
+}
+
+// Document is a parsed executable document
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
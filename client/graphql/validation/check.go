@@ -0,0 +1,74 @@
+package validation
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// readDir returns the path and contents of every .graphql file of dir, in name order
+func readDir(fsys fs.FS, dir string) ([]string, map[string]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var names []string
+	contents := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".graphql") {
+			continue
+		}
+		name := path.Join(dir, entry.Name())
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		names = append(names, name)
+		contents[name] = string(content)
+	}
+	slices.Sort(names)
+	return names, contents, nil
+}
+
+// LoadSchema parses every .graphql file of dir into a single schema
+func LoadSchema(fsys fs.FS, dir string) (*Schema, error) {
+	names, contents, err := readDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema files: %w", err)
+	}
+
+	schema := NewSchema()
+	for _, name := range names {
+		if err := ParseSchema(schema, name, contents[name]); err != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+// Check loads the schema of schemaDir and validates it, along with every query document of queriesDir.
+// Each query file is validated on its own, it must define the fragments it spreads. The returned error
+// is only set when the files can't be read or parsed
+func Check(fsys fs.FS, schemaDir, queriesDir string) ([]*Error, error) {
+	schema, err := LoadSchema(fsys, schemaDir)
+	if err != nil {
+		return nil, err
+	}
+	errs := schema.Validate()
+
+	names, contents, err := readDir(fsys, queriesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query files: %w", err)
+	}
+	for _, name := range names {
+		doc, err := ParseQuery(name, contents[name])
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, Validate(schema, doc)...)
+	}
+	return errs, nil
+}
@@ -0,0 +1,4 @@
+// Package validation checks the embedded GraphQL queries against the local schema files, so that fields
+// the schema doesn't declare, undefined fragments and type mismatches are caught before the generated
+// types silently drop data
+package validation
@@ -0,0 +1,17 @@
+package validation
+
+import (
+	"os"
+	"testing"
+)
+
+// TestEmbeddedQueries fails the build when the embedded queries drift from the schema files
+func TestEmbeddedQueries(t *testing.T) {
+	errs, err := Check(os.DirFS(".."), "schema", "queries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range errs {
+		t.Error(e)
+	}
+}
@@ -0,0 +1,188 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tokenKind identifies the lexical class of a token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+// token is a lexical unit of a GraphQL document
+type token struct {
+	kind  tokenKind
+	value string
+	pos   Position
+}
+
+// lexer splits a GraphQL document into tokens, skipping whitespace, commas and comments
+type lexer struct {
+	src  string
+	file string
+	off  int
+	line int
+	col  int
+}
+
+func newLexer(file, src string) *lexer {
+	return &lexer{src: src, file: file, line: 1, col: 1}
+}
+
+// advance moves past n bytes, keeping track of lines and columns
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.off < len(l.src); i++ {
+		if l.src[l.off] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.off++
+	}
+}
+
+// skipIgnored moves past whitespace, commas, the unicode BOM and comments
+func (l *lexer) skipIgnored() {
+	for l.off < len(l.src) {
+		switch c := l.src[l.off]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.advance(1)
+		case c == '#':
+			for l.off < len(l.src) && l.src[l.off] != '\n' {
+				l.advance(1)
+			}
+		case strings.HasPrefix(l.src[l.off:], "\uFEFF"):
+			l.off += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+// next returns the following token of the document
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	pos := Position{File: l.file, Line: l.line, Column: l.col}
+	if l.off >= len(l.src) {
+		return token{kind: tokenEOF, pos: pos}, nil
+	}
+
+	c := l.src[l.off]
+	switch {
+	case strings.HasPrefix(l.src[l.off:], "..."):
+		l.advance(3)
+		return token{kind: tokenPunct, value: "...", pos: pos}, nil
+	case strings.ContainsRune("!$&():=@[]{}|", rune(c)):
+		l.advance(1)
+		return token{kind: tokenPunct, value: string(c), pos: pos}, nil
+	case isNameStart(c):
+		start := l.off
+		for l.off < len(l.src) && isNameContinue(l.src[l.off]) {
+			l.advance(1)
+		}
+		return token{kind: tokenName, value: l.src[start:l.off], pos: pos}, nil
+	case c == '-' || isDigit(c):
+		return l.number(pos)
+	case c == '"':
+		return l.string(pos)
+	}
+	return token{}, &Error{Pos: pos, Message: fmt.Sprintf("unexpected character %q", c)}
+}
+
+// number lexes an Int or Float value
+func (l *lexer) number(pos Position) (token, error) {
+	start := l.off
+	kind := tokenInt
+	if l.src[l.off] == '-' {
+		l.advance(1)
+	}
+	digits := func() int {
+		n := 0
+		for l.off < len(l.src) && isDigit(l.src[l.off]) {
+			l.advance(1)
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return token{}, &Error{Pos: pos, Message: "invalid number"}
+	}
+	if l.off < len(l.src) && l.src[l.off] == '.' {
+		kind = tokenFloat
+		l.advance(1)
+		if digits() == 0 {
+			return token{}, &Error{Pos: pos, Message: "invalid number"}
+		}
+	}
+	if l.off < len(l.src) && (l.src[l.off] == 'e' || l.src[l.off] == 'E') {
+		kind = tokenFloat
+		l.advance(1)
+		if l.off < len(l.src) && (l.src[l.off] == '+' || l.src[l.off] == '-') {
+			l.advance(1)
+		}
+		if digits() == 0 {
+			return token{}, &Error{Pos: pos, Message: "invalid number"}
+		}
+	}
+	return token{kind: kind, value: l.src[start:l.off], pos: pos}, nil
+}
+
+// string lexes a quoted or block string, the token value keeps the raw contents
+func (l *lexer) string(pos Position) (token, error) {
+	if strings.HasPrefix(l.src[l.off:], `"""`) {
+		l.advance(3)
+		start := l.off
+		for l.off < len(l.src) {
+			if strings.HasPrefix(l.src[l.off:], `\"""`) {
+				l.advance(4)
+				continue
+			}
+			if strings.HasPrefix(l.src[l.off:], `"""`) {
+				value := l.src[start:l.off]
+				l.advance(3)
+				return token{kind: tokenString, value: value, pos: pos}, nil
+			}
+			l.advance(1)
+		}
+		return token{}, &Error{Pos: pos, Message: "unterminated block string"}
+	}
+
+	l.advance(1)
+	start := l.off
+	for l.off < len(l.src) {
+		switch l.src[l.off] {
+		case '\\':
+			l.advance(2)
+			continue
+		case '\n':
+			return token{}, &Error{Pos: pos, Message: "unterminated string"}
+		case '"':
+			value := l.src[start:l.off]
+			l.advance(1)
+			return token{kind: tokenString, value: value, pos: pos}, nil
+		}
+		l.advance(1)
+	}
+	return token{}, &Error{Pos: pos, Message: "unterminated string"}
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
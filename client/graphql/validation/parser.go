@@ -0,0 +1,711 @@
+package validation
+
+import (
+	"fmt"
+	"slices"
+)
+
+// parser builds schema definitions and executable documents out of the tokens of a lexer
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func newParser(file, src string) (*parser, error) {
+	p := &parser{lex: newLexer(file, src)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// advance reads the next token
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// errorf reports a syntax error at the current token
+func (p *parser) errorf(format string, args ...any) error {
+	return &Error{Pos: p.tok.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// peek reports whether the current token is the punctuator or keyword value
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+// skip consumes the current token if it is the punctuator or keyword value
+func (p *parser) skip(kind tokenKind, value string) (bool, error) {
+	if !p.peek(kind, value) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+// expect consumes the punctuator or keyword value, failing if it isn't the current token
+func (p *parser) expect(kind tokenKind, value string) error {
+	if !p.peek(kind, value) {
+		return p.errorf("expected %q, found %q", value, p.tok.value)
+	}
+	return p.advance()
+}
+
+// name consumes a name token
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.errorf("expected a name, found %q", p.tok.value)
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+// description skips the optional description string of a schema definition
+func (p *parser) description() error {
+	if p.tok.kind == tokenString {
+		return p.advance()
+	}
+	return nil
+}
+
+// typeRef parses a type reference such as [String!]!
+func (p *parser) typeRef() (*TypeRef, error) {
+	var ref *TypeRef
+	if ok, err := p.skip(tokenPunct, "["); err != nil {
+		return nil, err
+	} else if ok {
+		elem, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, "]"); err != nil {
+			return nil, err
+		}
+		ref = &TypeRef{Elem: elem}
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		ref = &TypeRef{Name: name}
+	}
+
+	nonNull, err := p.skip(tokenPunct, "!")
+	ref.NonNull = nonNull
+	return ref, err
+}
+
+// value parses a literal or a variable reference, constant values don't accept variables
+func (p *parser) value(constant bool) (*Value, error) {
+	v := &Value{Pos: p.tok.pos, Raw: p.tok.value}
+	switch p.tok.kind {
+	case tokenInt:
+		v.Kind = ValueInt
+	case tokenFloat:
+		v.Kind = ValueFloat
+	case tokenString:
+		v.Kind = ValueString
+	case tokenName:
+		switch p.tok.value {
+		case "true", "false":
+			v.Kind = ValueBoolean
+		case "null":
+			v.Kind = ValueNull
+		default:
+			v.Kind = ValueEnum
+		}
+	case tokenPunct:
+		switch p.tok.value {
+		case "$":
+			if constant {
+				return nil, p.errorf("variables aren't allowed in constant values")
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			v.Kind = ValueVariable
+			v.Raw = name
+			return v, nil
+		case "[":
+			v.Kind = ValueList
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			for !p.peek(tokenPunct, "]") {
+				item, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				v.List = append(v.List, item)
+			}
+			return v, p.advance()
+		case "{":
+			v.Kind = ValueObject
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			for !p.peek(tokenPunct, "}") {
+				pos := p.tok.pos
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(tokenPunct, ":"); err != nil {
+					return nil, err
+				}
+				fieldValue, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				v.Fields = append(v.Fields, &ObjectField{Name: name, Value: fieldValue, Pos: pos})
+			}
+			return v, p.advance()
+		default:
+			return nil, p.errorf("expected a value, found %q", p.tok.value)
+		}
+	default:
+		return nil, p.errorf("expected a value, found end of file")
+	}
+	return v, p.advance()
+}
+
+// arguments parses an optional parenthesized argument list
+func (p *parser) arguments(constant bool) ([]*Argument, error) {
+	if ok, err := p.skip(tokenPunct, "("); err != nil || !ok {
+		return nil, err
+	}
+	var args []*Argument
+	for !p.peek(tokenPunct, ")") {
+		pos := p.tok.pos
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, ":"); err != nil {
+			return nil, err
+		}
+		value, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, &Argument{Name: name, Value: value, Pos: pos})
+	}
+	return args, p.advance()
+}
+
+// directives skips the directives applied to a definition or selection, they aren't validated
+func (p *parser) directives(constant bool) error {
+	for p.peek(tokenPunct, "@") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if _, err := p.arguments(constant); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseSchema parses a schema document and merges its definitions into schema
+func ParseSchema(schema *Schema, file, src string) error {
+	p, err := newParser(file, src)
+	if err != nil {
+		return err
+	}
+
+	for p.tok.kind != tokenEOF {
+		if err := p.description(); err != nil {
+			return err
+		}
+		extend, err := p.skip(tokenName, "extend")
+		if err != nil {
+			return err
+		}
+
+		pos := p.tok.pos
+		keyword, err := p.name()
+		if err != nil {
+			return err
+		}
+
+		switch keyword {
+		case "schema":
+			err = p.schemaDefinition(schema)
+		case "directive":
+			err = p.directiveDefinition()
+		case "scalar", "type", "interface", "union", "enum", "input":
+			err = p.typeDefinition(schema, keyword, extend, pos)
+		default:
+			err = &Error{Pos: pos, Message: fmt.Sprintf("unexpected %q", keyword)}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schemaDefinition parses the root operation types
+func (p *parser) schemaDefinition(schema *Schema) error {
+	if err := p.directives(true); err != nil {
+		return err
+	}
+	if err := p.expect(tokenPunct, "{"); err != nil {
+		return err
+	}
+	for !p.peek(tokenPunct, "}") {
+		operation, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(tokenPunct, ":"); err != nil {
+			return err
+		}
+		typeName, err := p.name()
+		if err != nil {
+			return err
+		}
+		switch operation {
+		case "query":
+			schema.QueryType = typeName
+		case "mutation":
+			schema.MutationType = typeName
+		case "subscription":
+			schema.SubscriptionType = typeName
+		default:
+			return p.errorf("unknown operation type %q", operation)
+		}
+	}
+	return p.advance()
+}
+
+// directiveDefinition skips a directive definition
+func (p *parser) directiveDefinition() error {
+	if err := p.expect(tokenPunct, "@"); err != nil {
+		return err
+	}
+	if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.peek(tokenPunct, "(") {
+		if _, err := p.inputValues("(", ")"); err != nil {
+			return err
+		}
+	}
+	if _, err := p.skip(tokenName, "repeatable"); err != nil {
+		return err
+	}
+	if err := p.expect(tokenName, "on"); err != nil {
+		return err
+	}
+	if _, err := p.skip(tokenPunct, "|"); err != nil {
+		return err
+	}
+	for {
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if ok, err := p.skip(tokenPunct, "|"); err != nil || !ok {
+			return err
+		}
+	}
+}
+
+// typeDefinition parses a named type, extensions are merged into the type they extend
+func (p *parser) typeDefinition(schema *Schema, keyword string, extend bool, pos Position) error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+
+	kind := map[string]TypeKind{
+		"scalar":    KindScalar,
+		"type":      KindObject,
+		"interface": KindInterface,
+		"union":     KindUnion,
+		"enum":      KindEnum,
+		"input":     KindInputObject,
+	}[keyword]
+
+	def, exists := schema.Types[name]
+	switch {
+	case extend && !exists:
+		return &Error{Pos: pos, Message: fmt.Sprintf("extension of undefined type %s", name)}
+	case extend && def.Kind != kind:
+		return &Error{Pos: pos, Message: fmt.Sprintf("%s %s can't be extended as %s", def.Kind, name, kind)}
+	case !extend && exists:
+		return &Error{Pos: pos, Message: fmt.Sprintf("type %s is already defined at %s", name, def.Pos)}
+	case !extend:
+		def = &TypeDefinition{
+			Kind:        kind,
+			Name:        name,
+			Fields:      make(map[string]*FieldDefinition),
+			InputFields: make(map[string]*InputValue),
+			EnumValues:  make(map[string]bool),
+			Pos:         pos,
+		}
+		schema.Types[name] = def
+	}
+
+	if (kind == KindObject || kind == KindInterface) && p.peek(tokenName, "implements") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if _, err := p.skip(tokenPunct, "&"); err != nil {
+			return err
+		}
+		for p.tok.kind == tokenName {
+			def.Interfaces = append(def.Interfaces, p.tok.value)
+			if err := p.advance(); err != nil {
+				return err
+			}
+			if _, err := p.skip(tokenPunct, "&"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := p.directives(true); err != nil {
+		return err
+	}
+
+	switch kind {
+	case KindObject, KindInterface:
+		if p.peek(tokenPunct, "{") {
+			return p.fieldDefinitions(def)
+		}
+	case KindInputObject:
+		if p.peek(tokenPunct, "{") {
+			fields, err := p.inputValues("{", "}")
+			if err != nil {
+				return err
+			}
+			for _, field := range fields {
+				def.InputFields[field.Name] = field
+			}
+		}
+	case KindUnion:
+		if ok, err := p.skip(tokenPunct, "="); err != nil || !ok {
+			return err
+		}
+		if _, err := p.skip(tokenPunct, "|"); err != nil {
+			return err
+		}
+		for {
+			member, err := p.name()
+			if err != nil {
+				return err
+			}
+			def.Members = append(def.Members, member)
+			if ok, err := p.skip(tokenPunct, "|"); err != nil || !ok {
+				return err
+			}
+		}
+	case KindEnum:
+		if ok, err := p.skip(tokenPunct, "{"); err != nil || !ok {
+			return err
+		}
+		for !p.peek(tokenPunct, "}") {
+			if err := p.description(); err != nil {
+				return err
+			}
+			value, err := p.name()
+			if err != nil {
+				return err
+			}
+			def.EnumValues[value] = true
+			if err := p.directives(true); err != nil {
+				return err
+			}
+		}
+		return p.advance()
+	}
+	return nil
+}
+
+// fieldDefinitions parses the fields of an object or interface type
+func (p *parser) fieldDefinitions(def *TypeDefinition) error {
+	if err := p.expect(tokenPunct, "{"); err != nil {
+		return err
+	}
+	for !p.peek(tokenPunct, "}") {
+		if err := p.description(); err != nil {
+			return err
+		}
+		field := &FieldDefinition{Pos: p.tok.pos, Arguments: make(map[string]*InputValue)}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		field.Name = name
+
+		if p.peek(tokenPunct, "(") {
+			args, err := p.inputValues("(", ")")
+			if err != nil {
+				return err
+			}
+			for _, arg := range args {
+				field.Arguments[arg.Name] = arg
+			}
+		}
+
+		if err := p.expect(tokenPunct, ":"); err != nil {
+			return err
+		}
+		if field.Type, err = p.typeRef(); err != nil {
+			return err
+		}
+		if err := p.directives(true); err != nil {
+			return err
+		}
+
+		if existing, ok := def.Fields[name]; ok {
+			return &Error{Pos: field.Pos, Message: fmt.Sprintf("field %s.%s is already defined at %s", def.Name, name, existing.Pos)}
+		}
+		def.Fields[name] = field
+	}
+	return p.advance()
+}
+
+// inputValues parses argument definitions or input object fields enclosed by open and close
+func (p *parser) inputValues(open, close string) ([]*InputValue, error) {
+	if err := p.expect(tokenPunct, open); err != nil {
+		return nil, err
+	}
+	var values []*InputValue
+	for !p.peek(tokenPunct, close) {
+		if err := p.description(); err != nil {
+			return nil, err
+		}
+		value := &InputValue{Pos: p.tok.pos}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		value.Name = name
+		if err := p.expect(tokenPunct, ":"); err != nil {
+			return nil, err
+		}
+		if value.Type, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if ok, err := p.skip(tokenPunct, "="); err != nil {
+			return nil, err
+		} else if ok {
+			if value.DefaultValue, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.directives(true); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, p.advance()
+}
+
+// ParseQuery parses an executable document holding operations and fragments
+func ParseQuery(file, src string) (*Document, error) {
+	p, err := newParser(file, src)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Fragments: make(map[string]*Fragment)}
+	for p.tok.kind != tokenEOF {
+		pos := p.tok.pos
+		switch {
+		case p.peek(tokenPunct, "{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Kind: "query", Selections: selections, Pos: pos})
+		case p.peek(tokenName, "fragment"):
+			fragment, err := p.fragmentDefinition()
+			if err != nil {
+				return nil, err
+			}
+			if existing, ok := doc.Fragments[fragment.Name]; ok {
+				return nil, &Error{Pos: pos, Message: fmt.Sprintf("fragment %s is already defined at %s", fragment.Name, existing.Pos)}
+			}
+			doc.Fragments[fragment.Name] = fragment
+		case p.tok.kind == tokenName && slices.Contains([]string{"query", "mutation", "subscription"}, p.tok.value):
+			operation, err := p.operationDefinition()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, operation)
+		default:
+			return nil, p.errorf("expected an operation or a fragment, found %q", p.tok.value)
+		}
+	}
+	return doc, nil
+}
+
+// operationDefinition parses a named or anonymous operation
+func (p *parser) operationDefinition() (*Operation, error) {
+	operation := &Operation{Kind: p.tok.value, Pos: p.tok.pos}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenName {
+		operation.Name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if ok, err := p.skip(tokenPunct, "("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.peek(tokenPunct, ")") {
+			variable := &VariableDefinition{Pos: p.tok.pos}
+			if err := p.expect(tokenPunct, "$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			variable.Name = name
+			if err := p.expect(tokenPunct, ":"); err != nil {
+				return nil, err
+			}
+			if variable.Type, err = p.typeRef(); err != nil {
+				return nil, err
+			}
+			if ok, err := p.skip(tokenPunct, "="); err != nil {
+				return nil, err
+			} else if ok {
+				if variable.DefaultValue, err = p.value(true); err != nil {
+					return nil, err
+				}
+			}
+			if err := p.directives(true); err != nil {
+				return nil, err
+			}
+			operation.Variables = append(operation.Variables, variable)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.directives(false); err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	operation.Selections = selections
+	return operation, err
+}
+
+// fragmentDefinition parses a named fragment
+func (p *parser) fragmentDefinition() (*Fragment, error) {
+	fragment := &Fragment{Pos: p.tok.pos}
+	if err := p.expect(tokenName, "fragment"); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	fragment.Name = name
+	if err := p.expect(tokenName, "on"); err != nil {
+		return nil, err
+	}
+	if fragment.TypeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.directives(false); err != nil {
+		return nil, err
+	}
+	fragment.Selections, err = p.selectionSet()
+	return fragment, err
+}
+
+// selectionSet parses a braced list of fields and fragments
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect(tokenPunct, "{"); err != nil {
+		return nil, err
+	}
+	var selections []Selection
+	for !p.peek(tokenPunct, "}") {
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return selections, p.advance()
+}
+
+// selection parses a field, a fragment spread or an inline fragment
+func (p *parser) selection() (Selection, error) {
+	pos := p.tok.pos
+	if ok, err := p.skip(tokenPunct, "..."); err != nil {
+		return nil, err
+	} else if ok {
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			spread := &FragmentSpread{Name: p.tok.value, Pos: pos}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			return spread, p.directives(false)
+		}
+
+		inline := &InlineFragment{Pos: pos}
+		if ok, err := p.skip(tokenName, "on"); err != nil {
+			return nil, err
+		} else if ok {
+			if inline.TypeCondition, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.directives(false); err != nil {
+			return nil, err
+		}
+		var err error
+		inline.Selections, err = p.selectionSet()
+		return inline, err
+	}
+
+	field := &Field{Pos: pos}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	field.Name = name
+	if ok, err := p.skip(tokenPunct, ":"); err != nil {
+		return nil, err
+	} else if ok {
+		field.Alias = name
+		if field.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if field.Arguments, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if err := p.directives(false); err != nil {
+		return nil, err
+	}
+	if p.peek(tokenPunct, "{") {
+		field.Selections, err = p.selectionSet()
+	}
+	return field, err
+}
@@ -0,0 +1,540 @@
+package validation
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Error is a problem found at a position of a schema or query file
+type Error struct {
+	Pos     Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Pos.Line == 0 {
+		return e.Message
+	}
+	return e.Pos.String() + ": " + e.Message
+}
+
+// NewSchema creates an empty schema holding the built-in scalars
+func NewSchema() *Schema {
+	schema := &Schema{
+		Types:            make(map[string]*TypeDefinition),
+		QueryType:        "Query",
+		MutationType:     "Mutation",
+		SubscriptionType: "Subscription",
+	}
+	for _, name := range []string{"Int", "Float", "String", "Boolean", "ID"} {
+		schema.Types[name] = &TypeDefinition{Kind: KindScalar, Name: name}
+	}
+	return schema
+}
+
+// isInputType reports whether typeName can be used for arguments and variables
+func (s *Schema) isInputType(typeName string) bool {
+	def, ok := s.Types[typeName]
+	return ok && (def.Kind == KindScalar || def.Kind == KindEnum || def.Kind == KindInputObject)
+}
+
+// IsComposite reports whether def has fields to select
+func IsComposite(def *TypeDefinition) bool {
+	return def.Kind == KindObject || def.Kind == KindInterface || def.Kind == KindUnion
+}
+
+// PossibleTypes returns the object types a value of typeName can be at runtime, in name order
+func (s *Schema) PossibleTypes(typeName string) []string {
+	def, ok := s.Types[typeName]
+	if !ok {
+		return nil
+	}
+	switch def.Kind {
+	case KindObject:
+		return []string{def.Name}
+	case KindUnion:
+		return slices.Sorted(slices.Values(def.Members))
+	case KindInterface:
+		var types []string
+		for _, candidate := range s.Types {
+			if candidate.Kind == KindObject && slices.Contains(candidate.Interfaces, def.Name) {
+				types = append(types, candidate.Name)
+			}
+		}
+		slices.Sort(types)
+		return types
+	}
+	return nil
+}
+
+// Validate reports the inconsistencies of the schema itself: references to undefined types, fields of
+// the wrong kind, interfaces not fully implemented and invalid union members
+func (s *Schema) Validate() []*Error {
+	var errs []*Error
+	report := func(pos Position, format string, args ...any) {
+		errs = append(errs, &Error{Pos: pos, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, def := range s.Types {
+		for _, field := range def.Fields {
+			if target, ok := s.Types[field.Type.NamedType()]; !ok {
+				report(field.Pos, "field %s.%s has undefined type %s", def.Name, field.Name, field.Type.NamedType())
+			} else if target.Kind == KindInputObject {
+				report(field.Pos, "field %s.%s can't be of input type %s", def.Name, field.Name, target.Name)
+			}
+			for _, arg := range field.Arguments {
+				if !s.isInputType(arg.Type.NamedType()) {
+					report(arg.Pos, "argument %s of %s.%s must be an input type, %s isn't", arg.Name, def.Name, field.Name, arg.Type.NamedType())
+				}
+			}
+		}
+
+		for _, field := range def.InputFields {
+			if !s.isInputType(field.Type.NamedType()) {
+				report(field.Pos, "input field %s.%s must be an input type, %s isn't", def.Name, field.Name, field.Type.NamedType())
+			}
+		}
+
+		for _, name := range def.Interfaces {
+			iface, ok := s.Types[name]
+			if !ok || iface.Kind != KindInterface {
+				report(def.Pos, "%s implements %s which isn't an interface", def.Name, name)
+				continue
+			}
+			for fieldName, ifaceField := range iface.Fields {
+				field, ok := def.Fields[fieldName]
+				if !ok {
+					report(def.Pos, "%s doesn't declare field %s required by interface %s", def.Name, fieldName, name)
+				} else if !s.isSubType(field.Type, ifaceField.Type) {
+					report(field.Pos, "field %s.%s is %s, which doesn't satisfy %s.%s of type %s",
+						def.Name, fieldName, field.Type, name, fieldName, ifaceField.Type)
+				}
+			}
+		}
+
+		for _, member := range def.Members {
+			if target, ok := s.Types[member]; !ok || target.Kind != KindObject {
+				report(def.Pos, "union %s member %s isn't an object type", def.Name, member)
+			}
+		}
+	}
+
+	if _, ok := s.Types[s.QueryType]; !ok {
+		report(Position{}, "schema has no %s type", s.QueryType)
+	}
+
+	errs = sortErrors(errs)
+	return errs
+}
+
+// isSubType reports whether a field of type t may implement an interface field of type of
+func (s *Schema) isSubType(t, of *TypeRef) bool {
+	switch {
+	case of.NonNull && !t.NonNull:
+		return false
+	case t.NonNull && !of.NonNull:
+		return s.isSubType(&TypeRef{Name: t.Name, Elem: t.Elem}, of)
+	case of.Elem != nil:
+		return t.Elem != nil && s.isSubType(t.Elem, of.Elem)
+	case t.Elem != nil:
+		return false
+	}
+	return t.Name == of.Name || slices.Contains(s.PossibleTypes(of.Name), t.Name) ||
+		(s.Types[t.Name] != nil && slices.Contains(s.Types[t.Name].Interfaces, of.Name))
+}
+
+// variableUsage is a variable reference found at a location expecting the type expected
+type variableUsage struct {
+	name            string
+	expected        *TypeRef
+	locationDefault bool
+	pos             Position
+}
+
+// validator checks an executable document against a schema
+type validator struct {
+	schema *Schema
+	doc    *Document
+	errs   []*Error
+	// usages holds the variables referenced directly by each operation and fragment
+	usages map[any][]variableUsage
+	// spreads holds the fragments spread directly by each operation and fragment
+	spreads map[any][]*FragmentSpread
+}
+
+func (v *validator) report(pos Position, format string, args ...any) {
+	v.errs = append(v.errs, &Error{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks every operation and fragment of doc against schema, reporting unknown fields and
+// arguments, undefined fragments and variables, and type mismatches
+func Validate(schema *Schema, doc *Document) []*Error {
+	v := &validator{
+		schema:  schema,
+		doc:     doc,
+		usages:  make(map[any][]variableUsage),
+		spreads: make(map[any][]*FragmentSpread),
+	}
+
+	for _, fragment := range doc.Fragments {
+		def, ok := schema.Types[fragment.TypeCondition]
+		if !ok {
+			v.report(fragment.Pos, "fragment %s is on undefined type %s", fragment.Name, fragment.TypeCondition)
+			continue
+		}
+		if !IsComposite(def) {
+			v.report(fragment.Pos, "fragment %s can't be on %s %s", fragment.Name, def.Kind, def.Name)
+			continue
+		}
+		v.selections(fragment, def, fragment.Selections)
+	}
+
+	names := make(map[string]bool)
+	for _, operation := range doc.Operations {
+		if operation.Name != "" {
+			if names[operation.Name] {
+				v.report(operation.Pos, "operation %s is defined more than once", operation.Name)
+			}
+			names[operation.Name] = true
+		} else if len(doc.Operations) > 1 {
+			v.report(operation.Pos, "anonymous operations must be the only operation of the document")
+		}
+
+		root := map[string]string{
+			"query":        schema.QueryType,
+			"mutation":     schema.MutationType,
+			"subscription": schema.SubscriptionType,
+		}[operation.Kind]
+		def, ok := schema.Types[root]
+		if !ok {
+			v.report(operation.Pos, "schema doesn't support %s operations", operation.Kind)
+			continue
+		}
+		v.selections(operation, def, operation.Selections)
+		v.variables(operation)
+	}
+
+	used := make(map[string]bool)
+	for _, operation := range doc.Operations {
+		for name := range v.reachableFragments(operation) {
+			used[name] = true
+		}
+	}
+	for _, fragment := range doc.Fragments {
+		if !used[fragment.Name] {
+			v.report(fragment.Pos, "fragment %s is never used", fragment.Name)
+		}
+	}
+
+	v.errs = sortErrors(v.errs)
+	return v.errs
+}
+
+// selections validates a selection set on the parent type, owner is the operation or fragment holding it
+func (v *validator) selections(owner any, parent *TypeDefinition, selections []Selection) {
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *Field:
+			v.field(owner, parent, s)
+
+		case *FragmentSpread:
+			v.spreads[owner] = append(v.spreads[owner], s)
+			fragment, ok := v.doc.Fragments[s.Name]
+			if !ok {
+				v.report(s.Pos, "undefined fragment %s", s.Name)
+				continue
+			}
+			if _, ok := v.schema.Types[fragment.TypeCondition]; ok && !v.overlap(parent.Name, fragment.TypeCondition) {
+				v.report(s.Pos, "fragment %s on %s can never apply to %s", s.Name, fragment.TypeCondition, parent.Name)
+			}
+
+		case *InlineFragment:
+			target := parent
+			if s.TypeCondition != "" {
+				def, ok := v.schema.Types[s.TypeCondition]
+				if !ok {
+					v.report(s.Pos, "inline fragment on undefined type %s", s.TypeCondition)
+					continue
+				}
+				if !IsComposite(def) {
+					v.report(s.Pos, "inline fragment can't be on %s %s", def.Kind, def.Name)
+					continue
+				}
+				if !v.overlap(parent.Name, def.Name) {
+					v.report(s.Pos, "inline fragment on %s can never apply to %s", def.Name, parent.Name)
+					continue
+				}
+				target = def
+			}
+			v.selections(owner, target, s.Selections)
+		}
+	}
+}
+
+// overlap reports whether a value of type a may also be of type b
+func (v *validator) overlap(a, b string) bool {
+	if a == b {
+		return true
+	}
+	possible := v.schema.PossibleTypes(b)
+	for _, t := range v.schema.PossibleTypes(a) {
+		if slices.Contains(possible, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// field validates a field selected on parent, its arguments and its sub-selections
+func (v *validator) field(owner any, parent *TypeDefinition, field *Field) {
+	if field.Name == "__typename" {
+		if len(field.Selections) > 0 {
+			v.report(field.Pos, "__typename can't have a selection set")
+		}
+		return
+	}
+	if parent.Kind == KindUnion {
+		v.report(field.Pos, "can't select field %s on union %s, use an inline fragment", field.Name, parent.Name)
+		return
+	}
+
+	def, ok := parent.Fields[field.Name]
+	if !ok {
+		v.report(field.Pos, "unknown field %s on type %s", field.Name, parent.Name)
+		return
+	}
+
+	passed := make(map[string]bool)
+	for _, arg := range field.Arguments {
+		if passed[arg.Name] {
+			v.report(arg.Pos, "argument %s is passed more than once", arg.Name)
+		}
+		passed[arg.Name] = true
+		argDef, ok := def.Arguments[arg.Name]
+		if !ok {
+			v.report(arg.Pos, "unknown argument %s on field %s.%s", arg.Name, parent.Name, field.Name)
+			continue
+		}
+		v.value(owner, arg.Value, argDef.Type, argDef.DefaultValue != nil)
+	}
+	for name, argDef := range def.Arguments {
+		if argDef.Type.NonNull && argDef.DefaultValue == nil && !passed[name] {
+			v.report(field.Pos, "field %s.%s is missing required argument %s", parent.Name, field.Name, name)
+		}
+	}
+
+	target, ok := v.schema.Types[def.Type.NamedType()]
+	if !ok {
+		// Reported by Schema.Validate
+		return
+	}
+	switch {
+	case IsComposite(target) && len(field.Selections) == 0:
+		v.report(field.Pos, "field %s of type %s must have a selection set", field.Name, def.Type)
+	case !IsComposite(target) && len(field.Selections) > 0:
+		v.report(field.Pos, "field %s of %s %s can't have a selection set", field.Name, target.Kind, target.Name)
+	case IsComposite(target):
+		v.selections(owner, target, field.Selections)
+	}
+}
+
+// value checks that a literal can be coerced to expected and records the variables it references
+func (v *validator) value(owner any, value *Value, expected *TypeRef, locationDefault bool) {
+	if value.Kind == ValueVariable {
+		v.usages[owner] = append(v.usages[owner], variableUsage{
+			name:            value.Raw,
+			expected:        expected,
+			locationDefault: locationDefault,
+			pos:             value.Pos,
+		})
+		return
+	}
+	if value.Kind == ValueNull {
+		if expected.NonNull {
+			v.report(value.Pos, "null isn't a valid %s", expected)
+		}
+		return
+	}
+
+	if expected.Elem != nil {
+		if value.Kind != ValueList {
+			// A single value is coerced into a list of one item
+			v.value(owner, value, expected.Elem, false)
+			return
+		}
+		for _, item := range value.List {
+			v.value(owner, item, expected.Elem, false)
+		}
+		return
+	}
+
+	def, ok := v.schema.Types[expected.Name]
+	if !ok {
+		return
+	}
+	mismatch := func() {
+		v.report(value.Pos, "%s isn't a valid %s", describe(value), expected)
+	}
+
+	switch def.Kind {
+	case KindScalar:
+		allowed := map[string][]ValueKind{
+			"Int":     {ValueInt},
+			"Float":   {ValueInt, ValueFloat},
+			"String":  {ValueString},
+			"Boolean": {ValueBoolean},
+			"ID":      {ValueString, ValueInt},
+		}
+		kinds, builtin := allowed[def.Name]
+		if builtin && !slices.Contains(kinds, value.Kind) {
+			mismatch()
+		}
+	case KindEnum:
+		if value.Kind != ValueEnum || (len(def.EnumValues) > 0 && !def.EnumValues[value.Raw]) {
+			mismatch()
+		}
+	case KindInputObject:
+		if value.Kind != ValueObject {
+			mismatch()
+			return
+		}
+		set := make(map[string]bool)
+		for _, field := range value.Fields {
+			set[field.Name] = true
+			fieldDef, ok := def.InputFields[field.Name]
+			if !ok {
+				v.report(field.Pos, "unknown field %s on input %s", field.Name, def.Name)
+				continue
+			}
+			v.value(owner, field.Value, fieldDef.Type, fieldDef.DefaultValue != nil)
+		}
+		for name, fieldDef := range def.InputFields {
+			if fieldDef.Type.NonNull && fieldDef.DefaultValue == nil && !set[name] {
+				v.report(value.Pos, "input %s is missing required field %s", def.Name, name)
+			}
+		}
+	default:
+		mismatch()
+	}
+}
+
+// describe names a literal in error messages
+func describe(value *Value) string {
+	switch value.Kind {
+	case ValueString:
+		return fmt.Sprintf("%q", value.Raw)
+	case ValueList:
+		return "list"
+	case ValueObject:
+		return "object"
+	}
+	return value.Raw
+}
+
+// variables checks the variable definitions of operation against the usages of the operation and the
+// fragments it spreads
+func (v *validator) variables(operation *Operation) {
+	defined := make(map[string]*VariableDefinition)
+	for _, variable := range operation.Variables {
+		if _, ok := defined[variable.Name]; ok {
+			v.report(variable.Pos, "variable $%s is defined more than once", variable.Name)
+		}
+		defined[variable.Name] = variable
+		if !v.schema.isInputType(variable.Type.NamedType()) {
+			v.report(variable.Pos, "variable $%s must be of an input type, %s isn't", variable.Name, variable.Type.NamedType())
+		}
+	}
+
+	owners := []any{operation}
+	for _, fragment := range v.reachableFragments(operation) {
+		owners = append(owners, fragment)
+	}
+
+	used := make(map[string]bool)
+	for _, owner := range owners {
+		for _, usage := range v.usages[owner] {
+			used[usage.name] = true
+			variable, ok := defined[usage.name]
+			if !ok {
+				v.report(usage.pos, "variable $%s isn't defined by operation %s", usage.name, operation.Name)
+				continue
+			}
+			hasDefault := variable.DefaultValue != nil && variable.DefaultValue.Kind != ValueNull
+			if !allowed(variable.Type, usage.expected, hasDefault, usage.locationDefault) {
+				v.report(usage.pos, "variable $%s of type %s can't be used where %s is expected",
+					usage.name, variable.Type, usage.expected)
+			}
+		}
+	}
+
+	for _, variable := range operation.Variables {
+		if !used[variable.Name] {
+			v.report(variable.Pos, "variable $%s is never used by operation %s", variable.Name, operation.Name)
+		}
+	}
+}
+
+// reachableFragments returns the fragments spread by owner, directly or through other fragments
+func (v *validator) reachableFragments(owner any) map[string]*Fragment {
+	reached := make(map[string]*Fragment)
+	var walk func(owner any, path []string)
+	walk = func(owner any, path []string) {
+		for _, spread := range v.spreads[owner] {
+			fragment, ok := v.doc.Fragments[spread.Name]
+			if !ok {
+				continue
+			}
+			if slices.Contains(path, fragment.Name) {
+				v.report(spread.Pos, "fragment %s spreads itself", fragment.Name)
+				continue
+			}
+			if _, ok := reached[fragment.Name]; ok {
+				continue
+			}
+			reached[fragment.Name] = fragment
+			walk(fragment, append(path, fragment.Name))
+		}
+	}
+	walk(owner, nil)
+	return reached
+}
+
+// allowed reports whether a variable of type variable can be used where expected is required
+func allowed(variable, expected *TypeRef, variableDefault, locationDefault bool) bool {
+	if expected.NonNull && !variable.NonNull {
+		if !variableDefault && !locationDefault {
+			return false
+		}
+		expected = &TypeRef{Name: expected.Name, Elem: expected.Elem}
+	}
+	return compatible(variable, expected)
+}
+
+// compatible reports whether a value of type t always satisfies of
+func compatible(t, of *TypeRef) bool {
+	switch {
+	case of.NonNull:
+		return t.NonNull && compatible(&TypeRef{Name: t.Name, Elem: t.Elem}, &TypeRef{Name: of.Name, Elem: of.Elem})
+	case t.NonNull:
+		return compatible(&TypeRef{Name: t.Name, Elem: t.Elem}, of)
+	case of.Elem != nil:
+		return t.Elem != nil && compatible(t.Elem, of.Elem)
+	case t.Elem != nil:
+		return false
+	}
+	return t.Name == of.Name
+}
+
+// sortErrors orders errors by file and position, and drops exact duplicates
+func sortErrors(errs []*Error) []*Error {
+	slices.SortFunc(errs, func(a, b *Error) int {
+		return cmp.Or(
+			cmp.Compare(a.Pos.File, b.Pos.File),
+			cmp.Compare(a.Pos.Line, b.Pos.Line),
+			cmp.Compare(a.Pos.Column, b.Pos.Column),
+			cmp.Compare(a.Message, b.Message),
+		)
+	})
+	return slices.CompactFunc(errs, func(a, b *Error) bool { return *a == *b })
+}
@@ -0,0 +1,114 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+type Query {
+  account(id: ID!): Account
+  accounts(first: Int, status: AccountStatus): [Account!]!
+}
+
+enum AccountStatus { OPEN CLOSED }
+
+type Account {
+  id: ID!
+  nickname: String
+  balance(currency: String!): Money
+}
+
+type Money {
+  amount: String!
+  cents: Int!
+}
+`
+
+func TestValidateRejects(t *testing.T) {
+	schema := NewSchema()
+	if err := ParseSchema(schema, "schema.graphql", testSchema); err != nil {
+		t.Fatal(err)
+	}
+	if errs := schema.Validate(); len(errs) > 0 {
+		t.Fatalf("invalid test schema: %v", errs)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			"valid",
+			`query Fetch($id: ID!) { account(id: $id) { id balance(currency: "CAD") { cents } } }`,
+			"",
+		},
+		{
+			"unknown field",
+			`query Fetch { accounts { id owner } }`,
+			"unknown field owner on type Account",
+		},
+		{
+			"undefined fragment",
+			`query Fetch { accounts { ...AccountFields } }`,
+			"undefined fragment AccountFields",
+		},
+		{
+			"variable type mismatch",
+			`query Fetch($id: String!) { account(id: $id) { id } }`,
+			"variable $id of type String! can't be used where ID! is expected",
+		},
+		{
+			"bad literal",
+			`query Fetch { accounts(first: "ten") { id } }`,
+			"isn't a valid Int",
+		},
+		{
+			"bad enum literal",
+			`query Fetch { accounts(status: FROZEN) { id } }`,
+			"isn't a valid AccountStatus",
+		},
+		{
+			"undeclared variable",
+			`query Fetch { account(id: $id) { id } }`,
+			"variable $id isn't defined by operation Fetch",
+		},
+		{
+			"scalar with a selection set",
+			`query Fetch { accounts { nickname { length } } }`,
+			"field nickname of scalar String can't have a selection set",
+		},
+		{
+			"object without a selection set",
+			`query Fetch { accounts }`,
+			"field accounts of type [Account!]! must have a selection set",
+		},
+		{
+			"missing required argument",
+			`query Fetch { accounts { balance { cents } } }`,
+			"field Account.balance is missing required argument currency",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseQuery("query.graphql", tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			errs := Validate(schema, doc)
+			if tt.want == "" {
+				for _, e := range errs {
+					t.Error(e)
+				}
+				return
+			}
+			for _, e := range errs {
+				if strings.Contains(e.Message, tt.want) {
+					return
+				}
+			}
+			t.Errorf("got %v, want an error containing %q", errs, tt.want)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GraphQLLocation points at the part of the query document an error relates to
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// GraphQLError is one entry of the "errors" array of a GraphQL response
+type GraphQLError struct {
+	Message    string            `json:"message"`
+	Path       []any             `json:"path,omitempty"`
+	Locations  []GraphQLLocation `json:"locations,omitempty"`
+	Extensions map[string]any    `json:"extensions,omitempty"`
+}
+
+// Code returns extensions.code, or an empty string when the server didn't set one
+func (e GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// PathString renders the error path in dot notation, e.g. "identity.accounts.edges.0"
+func (e GraphQLError) PathString() string {
+	parts := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		parts[i] = fmt.Sprint(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (e GraphQLError) Error() string {
+	msg := e.Message
+	if code := e.Code(); code != "" {
+		msg = fmt.Sprintf("%s [%s]", msg, code)
+	}
+	if len(e.Path) > 0 {
+		msg = fmt.Sprintf("%s at %s", msg, e.PathString())
+	}
+	return msg
+}
+
+// GraphQLErrors holds every error reported by a GraphQL response. It is returned alongside the
+// partial result when a response carries both data and errors
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("graphql: %s", strings.Join(msgs, "; "))
+}
+
+// Is lets errors.Is(err, ErrNotAuthorized) detect an expired token reported as a GraphQL error
+func (e GraphQLErrors) Is(target error) bool {
+	if target != ErrNotAuthorized {
+		return false
+	}
+	for _, err := range e {
+		if err.Message == notAuthorizedMessage || err.Code() == "UNAUTHENTICATED" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCode reports whether any of the errors has the given extensions.code
+func (e GraphQLErrors) HasCode(code string) bool {
+	return e.WithCode(code) != nil
+}
+
+// WithCode returns the first error with the given extensions.code, or nil
+func (e GraphQLErrors) WithCode(code string) *GraphQLError {
+	for i := range e {
+		if e[i].Code() == code {
+			return &e[i]
+		}
+	}
+	return nil
+}
+
+// Codes returns the distinct extensions.code values, in order of appearance
+func (e GraphQLErrors) Codes() []string {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, err := range e {
+		if code := err.Code(); code != "" && !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// HasGraphQLErrorCode reports whether err carries a GraphQL error with the given extensions.code
+func HasGraphQLErrorCode(err error, code string) bool {
+	var gqlErrs GraphQLErrors
+	return errors.As(err, &gqlErrs) && gqlErrs.HasCode(code)
+}
+
+// parseGraphQLErrors decodes the "errors" member of a GraphQL response
+func parseGraphQLErrors(raw any) (GraphQLErrors, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var gqlErrs GraphQLErrors
+	if err := json.Unmarshal(b, &gqlErrs); err != nil {
+		return nil, err
+	}
+	if len(gqlErrs) == 0 {
+		return nil, nil
+	}
+	return gqlErrs, nil
+}
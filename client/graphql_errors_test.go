@@ -0,0 +1,132 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/vpineda1996/wealthgo/client/graphql/queries"
+)
+
+func TestGraphQLErrorString(t *testing.T) {
+	err := GraphQLError{
+		Message:    "Account not found",
+		Path:       []any{"identity", "accounts", "edges", float64(2)},
+		Extensions: map[string]any{"code": "NOT_FOUND"},
+	}
+	if got, want := err.Error(), "Account not found [NOT_FOUND] at identity.accounts.edges.2"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	errs := GraphQLErrors{err, {Message: "Timeout", Extensions: map[string]any{"code": "TIMEOUT"}}, {Message: "Again", Extensions: map[string]any{"code": "NOT_FOUND"}}}
+	if !slices.Equal(errs.Codes(), []string{"NOT_FOUND", "TIMEOUT"}) {
+		t.Errorf("Codes() = %v", errs.Codes())
+	}
+	if got := errs.WithCode("TIMEOUT"); got == nil || got.Message != "Timeout" {
+		t.Errorf("WithCode(TIMEOUT) = %v", got)
+	}
+	if errs.HasCode("FORBIDDEN") {
+		t.Error("HasCode(FORBIDDEN) reported an absent code")
+	}
+}
+
+func TestGraphQLErrorsPartialData(t *testing.T) {
+	f, api := newBalanceAPI(t)
+	f.respond("FetchBalance", `{
+		"data": {"balance": {"amount": "1.00", "cents": 100, "currency": "CAD"}, "history": null},
+		"errors": [{
+			"message": "History unavailable",
+			"path": ["history"],
+			"locations": [{"line": 1, "column": 42}],
+			"extensions": {"code": "SERVICE_UNAVAILABLE"}
+		}]
+	}`)
+
+	balance, err := fetchBalance(api)
+	if balance["cents"] == nil {
+		t.Errorf("partial data was dropped: %v", balance)
+	}
+	var gqlErrs GraphQLErrors
+	if !errors.As(err, &gqlErrs) || len(gqlErrs) != 1 {
+		t.Fatalf("err = %v, want the GraphQLErrors of the response", err)
+	}
+	if got := gqlErrs[0]; got.PathString() != "history" || got.Locations[0] != (GraphQLLocation{Line: 1, Column: 42}) {
+		t.Errorf("error = %+v", got)
+	}
+	if !HasGraphQLErrorCode(err, "SERVICE_UNAVAILABLE") {
+		t.Error("HasGraphQLErrorCode missed SERVICE_UNAVAILABLE")
+	}
+}
+
+func TestGraphQLErrorsMissingValue(t *testing.T) {
+	f, api := newBalanceAPI(t)
+	f.respond("FetchBalance", `{"data":{"balance":null},"errors":[{"message":"Balance unavailable","path":["balance"]}]}`)
+
+	if _, err := fetchBalance(api); !errors.As(err, new(GraphQLErrors)) {
+		t.Fatalf("err = %v, want the errors explaining the missing value", err)
+	}
+}
+
+func TestGraphQLErrorsWithoutData(t *testing.T) {
+	f, api := newBalanceAPI(t)
+	f.respond("FetchBalance", `{"data":null,"errors":[{"message":"Invalid query","extensions":{"code":"GRAPHQL_VALIDATION_FAILED"}}]}`)
+
+	_, err := fetchBalance(api)
+	var wsErr *WSAPIError
+	if !errors.As(err, &wsErr) || !errors.Is(err, ErrWSApi) {
+		t.Fatalf("err = %v, want a WSAPIError", err)
+	}
+	if !HasGraphQLErrorCode(err, "GRAPHQL_VALIDATION_FAILED") {
+		t.Errorf("GraphQL errors of %v are lost", err)
+	}
+}
+
+func TestGraphQLErrorsUnauthenticated(t *testing.T) {
+	f, api := newBalanceAPI(t)
+	f.handle("FetchBalance", func(w http.ResponseWriter, req *fakeRequest) {
+		if req.Header.Get("Authorization") == "Bearer access-0" {
+			writeJSON(w, http.StatusOK, `{"data":null,"errors":[{"message":"Token expired","extensions":{"code":"UNAUTHENTICATED"}}]}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, balanceResponse)
+	})
+
+	// The expired token is reported as a GraphQL error of a 200 response rather than a 401
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.tokenRequests.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want 1", got)
+	}
+}
+
+func TestGetSecurityMarketDataPartialData(t *testing.T) {
+	f := newFakeAPI(t)
+	f.respond("FetchSecurityMarketData", `{
+		"data": {"security": {"id": "sec-s-1", "stock": {"symbol": "VFV", "primaryExchange": "TSX"}, "quote": null}},
+		"errors": [{"message": "Quote unavailable", "path": ["security", "quote"]}]
+	}`)
+	api := f.newClient()
+	cached := 0
+	api.SetSecurityMarketDataCache(
+		func(string) (*queries.SecurityMarketData, bool) { return nil, false },
+		func(string, *queries.SecurityMarketData) { cached++ },
+	)
+
+	security, err := api.GetSecurityMarketData("sec-s-1", true)
+	if !errors.As(err, new(GraphQLErrors)) {
+		t.Fatalf("err = %v, want GraphQLErrors", err)
+	}
+	if security == nil || security.Stock == nil || security.Stock.Symbol != "VFV" {
+		t.Fatalf("partial data was dropped: %+v", security)
+	}
+	if cached != 0 {
+		t.Error("partial data was cached")
+	}
+
+	// The symbol doesn't depend on the missing quote
+	if symbol, err := api.SecurityIDToSymbol("sec-s-1"); err != nil || symbol != "TSX:VFV" {
+		t.Errorf("SecurityIDToSymbol = %q, %v", symbol, err)
+	}
+}
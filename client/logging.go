@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders are masked whenever request headers are logged
+var sensitiveHeaders = map[string]bool{
+	"authorization":      true,
+	"x-wealthsimple-otp": true,
+	"x-ws-device-id":     true,
+	"cookie":             true,
+	"set-cookie":         true,
+}
+
+// sensitiveFields are masked whenever a request payload is logged
+var sensitiveFields = map[string]bool{
+	"password":      true,
+	"refresh_token": true,
+	"access_token":  true,
+	"otp_claim":     true,
+	"client_secret": true,
+	"username":      true,
+}
+
+// discardHandler drops every record, it is the default so the library stays quiet
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+var discardLogger = slog.New(discardHandler{})
+
+// redactedHeaders logs HTTP headers with credentials masked
+type redactedHeaders http.Header
+
+func (h redactedHeaders) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(h))
+	for k, v := range h {
+		value := strings.Join(v, ", ")
+		if sensitiveHeaders[strings.ToLower(k)] {
+			value = redactHeaderValue(value)
+		}
+		attrs = append(attrs, slog.String(k, value))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// redactHeaderValue masks a header value, keeping the auth scheme so logs stay readable
+func redactHeaderValue(value string) string {
+	if scheme, _, ok := strings.Cut(value, " "); ok && strings.EqualFold(scheme, "bearer") {
+		return scheme + " " + redacted
+	}
+	return redacted
+}
+
+// redactedPayload logs a request payload with credentials masked
+type redactedPayload map[string]any
+
+func (p redactedPayload) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(p))
+	for k, v := range p {
+		if k == "query" {
+			// Query documents are large and static, the operation name identifies them
+			continue
+		}
+		if nested, ok := v.(map[string]any); ok && !sensitiveFields[strings.ToLower(k)] {
+			attrs = append(attrs, slog.Any(k, redactedPayload(nested)))
+			continue
+		}
+		attrs = append(attrs, slog.Any(k, redactValue(k, v)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// redactValue masks v when key names a credential. Lists are copied with the objects they hold masked, as
+// handlers don't resolve the LogValuers nested in them
+func redactValue(key string, v any) any {
+	if v == nil {
+		return nil
+	}
+	if sensitiveFields[strings.ToLower(key)] {
+		return redacted
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[k] = redactValue(k, item)
+		}
+		return m
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = redactValue("", item)
+		}
+		return items
+	}
+	return v
+}
+
+// logger returns the configured logger, falling back to one that discards everything
+func (api *WealthsimpleAPIBase) logger() *slog.Logger {
+	if api.Logger != nil {
+		return api.Logger
+	}
+	return discardLogger
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// newJSONLogger logs every record as JSON into the returned buffer
+func newJSONLogger() (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})), &buf
+}
+
+// assertRedacted fails when the log holds any of secrets
+func assertRedacted(t *testing.T, log string, secrets ...string) {
+	t.Helper()
+	if !strings.Contains(log, redacted) {
+		t.Fatalf("nothing was redacted in %s", log)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(log, secret) {
+			t.Errorf("log holds %s:\n%s", secret, log)
+		}
+	}
+}
+
+func TestLoginLogsRedacted(t *testing.T) {
+	ctx := context.Background()
+	s := newOTPServer(t)
+	logger, buf := newJSONLogger()
+	api, err := NewClient(WithOAuthBaseURL(s.URL), WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.LoginInternalWithContext(ctx, "user", "secret", "", nil, "")
+	var challenge *LoginChallenge
+	if !errors.As(err, &challenge) {
+		t.Fatalf("err = %v, want a challenge", err)
+	}
+	if _, err := challenge.Submit(ctx, "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertRedacted(t, buf.String(), `"user"`, "secret", "123456", "claim-1", "access-1", "refresh-1")
+}
+
+func TestRefreshLogsRedacted(t *testing.T) {
+	logger, buf := newJSONLogger()
+	_, api := newBalanceAPI(t, WithLogger(logger))
+
+	if err := api.refreshAccessToken(context.Background(), "access-0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+
+	assertRedacted(t, buf.String(), "access-0", "refresh-0", "access-1", "refresh-1")
+}
+
+func TestRedactedPayloadLists(t *testing.T) {
+	logger, buf := newJSONLogger()
+	logger.Info("payload", slog.Any("payload", redactedPayload{
+		"operationName": "UpdateCredentials",
+		"variables": map[string]any{
+			"credentials": []any{
+				map[string]any{"username": "jane@example.com", "password": "hunter2"},
+				[]any{map[string]any{"refresh_token": "refresh-0"}},
+			},
+		},
+	}))
+
+	log := buf.String()
+	assertRedacted(t, log, "jane@example.com", "hunter2", "refresh-0")
+	if !strings.Contains(log, "UpdateCredentials") {
+		t.Errorf("log lost the operation name:\n%s", log)
+	}
+}
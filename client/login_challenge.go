@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Challenge types of a LoginChallenge
+const (
+	ChallengeOTP = "otp"
+)
+
+// Headers of the OTP exchange of the token endpoint
+const (
+	otpHeader      = "x-wealthsimple-otp"
+	otpClaimHeader = "x-wealthsimple-otp-claim"
+)
+
+// LoginChallenge is returned as the error of Login when the credentials need a second step to be accepted.
+// An OTP challenge matches ErrOTPRequired and is answered with Submit, which reuses the credentials and the
+// OTP claim of the first attempt so the code isn't sent again. Any other type matches ErrChallengeRequired
+// and can't be completed through the API
+type LoginChallenge struct {
+	// Type is ChallengeOTP, or the OAuth error naming the challenge otherwise
+	Type string
+	// Methods lists the ways the OTP can be delivered, e.g. "sms", "app" or "email", when the server tells
+	Methods []string
+	// Err is the response of the token endpoint that raised the challenge
+	Err *WSAPIError
+
+	api    *WealthsimpleAPIBase
+	client *WealthsimpleAPI
+	// credentials are cleared once the challenge is answered or discarded
+	credentials loginCredentials
+	claim       string
+}
+
+// loginCredentials are the parameters of a password grant
+type loginCredentials struct {
+	username          string
+	password          string
+	scope             string
+	persistSessionFct func(string) error
+}
+
+func (c *LoginChallenge) Error() string {
+	if c.Type == ChallengeOTP {
+		if len(c.Methods) > 0 {
+			return fmt.Sprintf("%v via %s", ErrOTPRequired, strings.Join(c.Methods, ", "))
+		}
+		return ErrOTPRequired.Error()
+	}
+	return fmt.Sprintf("%v: %s", ErrChallengeRequired, c.Type)
+}
+
+// Is matches ErrOTPRequired for OTP challenges and ErrChallengeRequired for the others
+func (c *LoginChallenge) Is(target error) bool {
+	if c.Type == ChallengeOTP {
+		return target == ErrOTPRequired
+	}
+	return target == ErrChallengeRequired
+}
+
+// Submit answers an OTP challenge. It returns the client Login would have returned, or nil for a challenge
+// raised by LoginInternal, whose API then holds the session. A rejected answer returns an error matching
+// ErrOTPInvalid and the challenge can be submitted again
+func (c *LoginChallenge) Submit(ctx context.Context, otpAnswer string) (*WealthsimpleAPI, error) {
+	if c.Type != ChallengeOTP {
+		return nil, fmt.Errorf("%w: %s challenges can't be answered with an OTP", ErrChallengeRequired, c.Type)
+	}
+	if c.credentials.password == "" {
+		return nil, fmt.Errorf("%w: challenge already answered or discarded", ErrUnexpected)
+	}
+	if otpAnswer == "" {
+		return nil, fmt.Errorf("%w: empty OTP answer", ErrOTPInvalid)
+	}
+
+	_, err := c.api.login(ctx, c.credentials, otpAnswer, c.claim)
+	if err != nil {
+		var challenge *LoginChallenge
+		if errors.As(err, &challenge) && challenge.claim != "" {
+			// A new code was issued, the next answer goes with its claim
+			c.claim = challenge.claim
+		}
+		return nil, err
+	}
+
+	c.credentials = loginCredentials{}
+	return c.client, nil
+}
+
+// Discard clears the credentials kept to answer the challenge, call it when the challenge is abandoned.
+// Submit fails afterwards
+func (c *LoginChallenge) Discard() {
+	c.credentials = loginCredentials{}
+	c.claim = ""
+}
+
+// newLoginChallenge builds the challenge raised by the token endpoint response wsErr
+func newLoginChallenge(api *WealthsimpleAPIBase, credentials loginCredentials, challengeType string, wsErr *WSAPIError) *LoginChallenge {
+	challenge := &LoginChallenge{
+		Type:        challengeType,
+		Err:         wsErr,
+		api:         api,
+		credentials: credentials,
+	}
+	if challengeType == ChallengeOTP {
+		challenge.Methods, challenge.claim = parseOTPHeaders(wsErr.Header)
+	}
+	return challenge
+}
+
+// parseOTPHeaders reads the delivery methods from a "required; method=sms" style x-wealthsimple-otp
+// header, and the claim identifying the code that was sent
+func parseOTPHeaders(header http.Header) (methods []string, claim string) {
+	if header == nil {
+		return nil, ""
+	}
+	for _, param := range strings.Split(header.Get(otpHeader), ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !strings.EqualFold(key, "method") {
+			continue
+		}
+		for _, method := range strings.Split(value, ",") {
+			if method = strings.TrimSpace(method); method != "" {
+				methods = append(methods, method)
+			}
+		}
+	}
+	return methods, header.Get(otpClaimHeader)
+}
+
+// challengeType returns the challenge raised by an OAuth error of the token endpoint, or "" if the error
+// is a plain failure. Other challenges than OTP are reported as "<name>_required" errors. An OTP is required
+// on an otp_required error or a "required" x-wealthsimple-otp header, and an invalid_grant answering
+// credentials sent without OTP is taken as a request for one unless the header says otherwise. Bad
+// credentials can't be told apart in that case, they are rejected once the OTP is submitted
+func challengeType(oauthError string, otpAnswer string, header http.Header) string {
+	switch {
+	case oauthError == "otp_required" || oauthError == "mfa_required":
+		return ChallengeOTP
+	case strings.HasSuffix(oauthError, "_required"):
+		return oauthError
+	case otpAnswer != "":
+		return ""
+	case otpRequired(header):
+		return ChallengeOTP
+	case oauthError == "invalid_grant" && header.Get(otpHeader) == "":
+		return ChallengeOTP
+	}
+	return ""
+}
+
+// otpRequired reports whether a "required; method=sms" style x-wealthsimple-otp header asks for an OTP
+func otpRequired(header http.Header) bool {
+	if header == nil {
+		return false
+	}
+	value, _, _ := strings.Cut(header.Get(otpHeader), ";")
+	return strings.EqualFold(strings.TrimSpace(value), "required")
+}
@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestChallengeType(t *testing.T) {
+	otpHeaders := http.Header{}
+	otpHeaders.Set(otpHeader, "required; method=sms")
+
+	tests := []struct {
+		name       string
+		oauthError string
+		otpAnswer  string
+		header     http.Header
+		want       string
+	}{
+		{"invalid_grant without OTP header", "invalid_grant", "", nil, ChallengeOTP},
+		{"bad credentials with an optional OTP header", "invalid_grant", "", http.Header{http.CanonicalHeaderKey(otpHeader): {"optional"}}, ""},
+		{"OTP header", "invalid_grant", "", otpHeaders, ChallengeOTP},
+		{"rejected OTP", "invalid_grant", "123456", otpHeaders, ""},
+		{"rejected OTP without OTP header", "invalid_grant", "123456", nil, ""},
+		{"otp_required", "otp_required", "", nil, ChallengeOTP},
+		{"mfa_required", "mfa_required", "", nil, ChallengeOTP},
+		{"other challenge", "device_verification_required", "", nil, "device_verification_required"},
+		{"other error", "invalid_client", "", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := challengeType(tt.oauthError, tt.otpAnswer, tt.header); got != tt.want {
+				t.Errorf("challengeType(%q, %q) = %q, want %q", tt.oauthError, tt.otpAnswer, got, tt.want)
+			}
+		})
+	}
+}
+
+// otpServer is a token endpoint accepting secret/123456, the OTP of the claim it sent with the challenge
+type otpServer struct {
+	*httptest.Server
+	logins atomic.Int32
+}
+
+func newOTPServer(t *testing.T) *otpServer {
+	s := &otpServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.logins.Add(1)
+		var form map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&form)
+		if form["username"] != "user" || form["password"] != "secret" {
+			writeJSON(w, http.StatusUnauthorized, `{"error":"invalid_grant","error_description":"Invalid credentials"}`)
+			return
+		}
+
+		otp := r.Header.Get(otpHeader)
+		if otp == "" {
+			w.Header().Set(otpHeader, "required; method=sms,app")
+			w.Header().Set(otpClaimHeader, "claim-1")
+			writeJSON(w, http.StatusUnauthorized, `{"error":"invalid_grant","error_description":"OTP required"}`)
+			return
+		}
+		if otp != "123456;remember=true" || form["otp_claim"] != "claim-1" {
+			writeJSON(w, http.StatusUnauthorized, `{"error":"invalid_grant","error_description":"Invalid OTP"}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"access_token":"access-1","refresh_token":"refresh-1","expires_in":1800}`)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *otpServer) newClient(t *testing.T) *WealthsimpleAPI {
+	api, err := NewClient(WithOAuthBaseURL(s.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	ctx := context.Background()
+	s := newOTPServer(t)
+	api := s.newClient(t)
+
+	// A plain invalid_grant can't be told apart from a request for an OTP, the credentials are rejected once
+	// the OTP is submitted
+	_, err := api.LoginInternalWithContext(ctx, "user", "wrong", "", nil, "")
+	var challenge *LoginChallenge
+	if !errors.As(err, &challenge) || challenge.Type != ChallengeOTP {
+		t.Fatalf("err = %v, want an OTP challenge", err)
+	}
+	if _, err := challenge.Submit(ctx, "123456"); !errors.Is(err, ErrLoginFailed) {
+		t.Errorf("err = %v, want ErrLoginFailed", err)
+	}
+}
+
+func TestLoginOTPNotRequired(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(otpHeader, "optional")
+		writeJSON(w, http.StatusUnauthorized, `{"error":"invalid_grant","error_description":"Invalid credentials"}`)
+	}))
+	defer s.Close()
+	api, err := NewClient(WithOAuthBaseURL(s.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.LoginInternalWithContext(context.Background(), "user", "wrong", "", nil, "")
+	if !errors.Is(err, ErrLoginFailed) {
+		t.Errorf("err = %v, want ErrLoginFailed", err)
+	}
+	var challenge *LoginChallenge
+	if errors.As(err, &challenge) {
+		t.Errorf("bad credentials returned a %s challenge", challenge.Type)
+	}
+}
+
+func TestLoginOTPWithoutHeader(t *testing.T) {
+	// The token endpoint asks for the OTP with a plain invalid_grant, without x-wealthsimple-otp header or claim
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(otpHeader) != "123456;remember=true" {
+			writeJSON(w, http.StatusUnauthorized, `{"error":"invalid_grant"}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"access_token":"access-1","refresh_token":"refresh-1","expires_in":1800}`)
+	}))
+	defer s.Close()
+	api, err := NewClient(WithOAuthBaseURL(s.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	_, err = api.LoginInternalWithContext(ctx, "user", "secret", "", nil, "")
+	var challenge *LoginChallenge
+	if !errors.As(err, &challenge) || !errors.Is(err, ErrOTPRequired) {
+		t.Fatalf("err = %v, want an OTP challenge", err)
+	}
+	if len(challenge.Methods) != 0 {
+		t.Errorf("Methods = %v, want none", challenge.Methods)
+	}
+	if _, err := challenge.Submit(ctx, "123456"); err != nil {
+		t.Fatal(err)
+	}
+	if got := api.CurrentSession().AccessToken; got != "access-1" {
+		t.Errorf("access token = %q, want access-1", got)
+	}
+}
+
+func TestLoginChallengeSubmit(t *testing.T) {
+	ctx := context.Background()
+	s := newOTPServer(t)
+	api := s.newClient(t)
+
+	_, err := api.LoginInternalWithContext(ctx, "user", "secret", "", nil, "")
+	var challenge *LoginChallenge
+	if !errors.As(err, &challenge) || !errors.Is(err, ErrOTPRequired) {
+		t.Fatalf("err = %v, want an OTP challenge", err)
+	}
+	if !slices.Equal(challenge.Methods, []string{"sms", "app"}) {
+		t.Errorf("Methods = %v", challenge.Methods)
+	}
+
+	if _, err := challenge.Submit(ctx, "000000"); !errors.Is(err, ErrOTPInvalid) {
+		t.Fatalf("wrong OTP: err = %v, want ErrOTPInvalid", err)
+	}
+	if _, err := challenge.Submit(ctx, "123456"); err != nil {
+		t.Fatalf("right OTP: %v", err)
+	}
+	if got := api.CurrentSession().AccessToken; got != "access-1" {
+		t.Errorf("access token = %q, want access-1", got)
+	}
+
+	logins := s.logins.Load()
+	if _, err := challenge.Submit(ctx, "123456"); err == nil {
+		t.Error("answered challenge was submitted again")
+	}
+	if s.logins.Load() != logins {
+		t.Error("answered challenge sent the credentials again")
+	}
+}
+
+func TestLoginChallengeDiscard(t *testing.T) {
+	ctx := context.Background()
+	s := newOTPServer(t)
+	api := s.newClient(t)
+
+	_, err := api.LoginInternalWithContext(ctx, "user", "secret", "", nil, "")
+	var challenge *LoginChallenge
+	if !errors.As(err, &challenge) {
+		t.Fatalf("err = %v, want a challenge", err)
+	}
+
+	challenge.Discard()
+	if challenge.credentials.username != "" || challenge.credentials.password != "" || challenge.claim != "" {
+		t.Error("Discard kept the credentials")
+	}
+	logins := s.logins.Load()
+	if _, err := challenge.Submit(ctx, "123456"); err == nil {
+		t.Error("discarded challenge was submitted")
+	}
+	if s.logins.Load() != logins {
+		t.Error("discarded challenge sent a request")
+	}
+}
+
+func TestLoginOtherChallenge(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusUnauthorized, `{"error":"device_verification_required"}`)
+	}))
+	defer s.Close()
+	api, err := NewClient(WithOAuthBaseURL(s.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.LoginInternalWithContext(context.Background(), "user", "secret", "", nil, "")
+	if !errors.Is(err, ErrChallengeRequired) || errors.Is(err, ErrOTPRequired) {
+		t.Fatalf("err = %v, want ErrChallengeRequired", err)
+	}
+	var challenge *LoginChallenge
+	if errors.As(err, &challenge) && challenge.Type != "device_verification_required" {
+		t.Errorf("Type = %q", challenge.Type)
+	}
+	if _, err := challenge.Submit(context.Background(), "123456"); !errors.Is(err, ErrChallengeRequired) {
+		t.Errorf("Submit: err = %v, want ErrChallengeRequired", err)
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/vpineda1996/wealthgo/client/graphql/queries"
+)
+
+// maxExactCents is 2^53+1, the first integer a float64 can't represent
+const maxExactCents = 9007199254740993
+
+const balanceResponse = `{"data":{"balance":{"amount":"90071992547409.93","cents":9007199254740993,"currency":"CAD"}}}`
+
+// newBalanceAPI serves a balance in cents that only survives decoding without going through float64
+func newBalanceAPI(t *testing.T, opts ...Option) (*fakeAPI, *WealthsimpleAPI) {
+	f := newFakeAPI(t)
+	f.respond("FetchBalance", balanceResponse)
+	api := f.newClient(opts...)
+	if err := api.RegisterQuery("FetchBalance", `query FetchBalance { balance { amount cents currency } }`); err != nil {
+		t.Fatal(err)
+	}
+	return f, api
+}
+
+func TestExactNumbersDoGraphQLQuery(t *testing.T) {
+	_, api := newBalanceAPI(t)
+
+	balance, err := DoGraphQLQuery[queries.Money](&api.WealthsimpleAPIBase, GraphQlQueryOpts{
+		QueryName:        "FetchBalance",
+		Variables:        map[string]any{},
+		DataResponsePath: "balance",
+		ExpectType:       reflect.TypeOf(map[string]any{}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Cents != maxExactCents {
+		t.Errorf("Cents = %d, want %d", balance.Cents, int64(maxExactCents))
+	}
+}
+
+func TestExactNumbersDoGraphQLOperation(t *testing.T) {
+	_, api := newBalanceAPI(t)
+
+	data, err := DoGraphQLOperation[struct {
+		Balance queries.Money `json:"balance"`
+	}](context.Background(), &api.WealthsimpleAPIBase, "FetchBalance", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Balance.Cents != maxExactCents {
+		t.Errorf("Cents = %d, want %d", data.Balance.Cents, int64(maxExactCents))
+	}
+}
+
+func TestExactNumbersSendHTTPRequest(t *testing.T) {
+	f, api := newBalanceAPI(t)
+
+	response, err := api.SendPost(f.server.URL+"/graphql", map[string]any{"operationName": "FetchBalance"}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := response.(map[string]any)["data"].(map[string]any)
+	balance, _ := data["balance"].(map[string]any)
+	cents, ok := balance["cents"].(json.Number)
+	if !ok {
+		t.Fatalf("cents decoded as %T, want json.Number", balance["cents"])
+	}
+	if got, err := cents.Int64(); err != nil || got != maxExactCents {
+		t.Errorf("cents = %s, want %d", cents, int64(maxExactCents))
+	}
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// DoGraphQLOperation runs one of the registered GraphQL operations and decodes the whole "data" member
+// into Data in a single pass, without going through an intermediate map. When the response carries both
+// data and errors, the partial data is returned along with a GraphQLErrors error
+func DoGraphQLOperation[Data any](ctx context.Context, api *WealthsimpleAPIBase, queryName string, variables map[string]any) (*Data, error) {
+	document, ok := api.Query(queryName)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown GraphQL operation %s", ErrUnexpected, queryName)
+	}
+	return runOperation[Data](ctx, api, queryName, document, variables)
+}
+
+// runOperation posts document and decodes its "data" member into Data, operationName may be empty
+// for anonymous documents
+func runOperation[Data any](ctx context.Context, api *WealthsimpleAPIBase, operationName, document string, variables map[string]any) (*Data, error) {
+	query := map[string]any{
+		"query":     document,
+		"variables": variables,
+	}
+	if operationName != "" {
+		query["operationName"] = operationName
+	}
+
+	response, err := postGraphQL[Data](ctx, api, query, api.graphQLHeaders(), isReadOnlyOperation(document))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Errors) > 0 {
+		return response.Data, response.Errors
+	}
+	return response.Data, nil
+}
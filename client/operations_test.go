@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/vpineda1996/wealthgo/client/graphql/queries"
+)
+
+// activityFeedResponse builds a FetchActivityFeedItems response of n activities
+func activityFeedResponse(n int) string {
+	var b strings.Builder
+	b.WriteString(`{"data":{"activityFeedItems":{"pageInfo":{"hasNextPage":false,"endCursor":null,"__typename":"PageInfo"},"edges":[`)
+	for i := range n {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"node":{"accountId":"tfsa-1","amount":"%d.25","amountSign":"positive","assetQuantity":"%d",`+
+			`"assetSymbol":"VFV","canonicalId":"activity-%d","currency":"CAD","occurredAt":"2024-01-02T15:04:05Z",`+
+			`"securityId":"sec-s-%d","status":"filled","subType":"MARKET_ORDER","type":"DIY_BUY","fees":"0",`+
+			`"fxRate":"1","interestRate":"0","strikePrice":"0","provisionalCreditAmount":"0",`+
+			`"counterPartyCurrencyAmount":"0","__typename":"ActivityFeedItem"},"__typename":"ActivityFeedItemEdge"}`, i, i%7, i, i%50)
+	}
+	b.WriteString(`],"__typename":"ActivityFeedItemConnection"}}}`)
+	return b.String()
+}
+
+func newActivityFeedAPI(tb testing.TB, n int) *WealthsimpleAPI {
+	f := newFakeAPI(tb)
+	f.respond("FetchActivityFeedItems", activityFeedResponse(n))
+	return f.newClient()
+}
+
+var activityFeedVariables = map[string]any{"first": 50, "condition": map[string]any{"accountIds": []string{"tfsa-1"}}}
+
+func TestDoGraphQLOperationMatchesDoGraphQLQuery(t *testing.T) {
+	api := newActivityFeedAPI(t, 20)
+
+	viaQuery, err := DoGraphQLQuery[[]queries.Activity](&api.WealthsimpleAPIBase, GraphQlQueryOpts{
+		QueryName:        "FetchActivityFeedItems",
+		Variables:        activityFeedVariables,
+		DataResponsePath: "activityFeedItems.edges",
+		ExpectType:       ArrayType,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	viaOperation, err := DoGraphQLOperation[queries.FetchActivityFeedItemsResponse](
+		context.Background(), &api.WealthsimpleAPIBase, "FetchActivityFeedItems", activityFeedVariables)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edges := viaOperation.ActivityFeedItems.Edges
+	if len(viaQuery) != 20 || len(edges) != 20 {
+		t.Fatalf("got %d and %d activities, want 20", len(viaQuery), len(edges))
+	}
+	for i, edge := range edges {
+		if *edge.Node.CanonicalId != *viaQuery[i].CanonicalId || edge.Node.Amount != viaQuery[i].Amount {
+			t.Errorf("activity %d differs: %+v and %+v", i, edge.Node, viaQuery[i])
+		}
+	}
+}
+
+func BenchmarkDoGraphQLQuery(b *testing.B) {
+	api := newActivityFeedAPI(b, 1000)
+	opts := GraphQlQueryOpts{
+		QueryName:        "FetchActivityFeedItems",
+		Variables:        activityFeedVariables,
+		DataResponsePath: "activityFeedItems.edges",
+		ExpectType:       ArrayType,
+	}
+
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := DoGraphQLQuery[[]queries.Activity](&api.WealthsimpleAPIBase, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDoGraphQLOperation(b *testing.B) {
+	api := newActivityFeedAPI(b, 1000)
+
+	b.ReportAllocs()
+	for range b.N {
+		_, err := DoGraphQLOperation[queries.FetchActivityFeedItemsResponse](
+			context.Background(), &api.WealthsimpleAPIBase, "FetchActivityFeedItems", activityFeedVariables)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// The benchmarks above include the HTTP round trip, the two below isolate the decoding of the response
+
+func BenchmarkDecodeGenericResponse(b *testing.B) {
+	body := activityFeedResponse(1000)
+	path, err := ParseResponsePath("activityFeedItems.edges")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for range b.N {
+		var response graphQLResponse[any]
+		if err := decodeJSON(strings.NewReader(body), &response); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := extractResponse[[]queries.Activity](*response.Data, path, ArrayType); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeTypedResponse(b *testing.B) {
+	body := activityFeedResponse(1000)
+
+	b.ReportAllocs()
+	for range b.N {
+		var response graphQLResponse[queries.FetchActivityFeedItemsResponse]
+		if err := decodeJSON(strings.NewReader(body), &response); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
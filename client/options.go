@@ -0,0 +1,186 @@
+package client
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures a WealthsimpleAPI instance at construction time
+type Option func(*WealthsimpleAPI)
+
+// WithHTTPClient sets the HTTP client used for every OAuth and GraphQL request
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(api *WealthsimpleAPI) {
+		if httpClient != nil {
+			api.HTTPClient = httpClient
+		}
+	}
+}
+
+// WithTransport sets the RoundTripper used for every OAuth and GraphQL request
+func WithTransport(transport http.RoundTripper) Option {
+	return func(api *WealthsimpleAPI) {
+		if transport != nil {
+			api.HTTPClient = &http.Client{Transport: transport}
+		}
+	}
+}
+
+// WithLogger sets the structured logger used to trace requests, secrets are redacted
+// before they reach the logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(api *WealthsimpleAPI) {
+		api.Logger = logger
+	}
+}
+
+// WithRetryPolicy sets how transient failures of read-only queries and token refreshes are retried
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(api *WealthsimpleAPI) {
+		api.RetryPolicy = &policy
+	}
+}
+
+// WithOAuthRateLimiter throttles requests sent to the OAuth endpoints
+func WithOAuthRateLimiter(limiter RateLimiter) Option {
+	return func(api *WealthsimpleAPI) {
+		api.OAuthRateLimiter = limiter
+	}
+}
+
+// WithGraphQLRateLimiter throttles requests sent to the GraphQL endpoint
+func WithGraphQLRateLimiter(limiter RateLimiter) Option {
+	return func(api *WealthsimpleAPI) {
+		api.GraphQLRateLimiter = limiter
+	}
+}
+
+// WithOAuthBaseURL overrides the base URL of the OAuth endpoints
+func WithOAuthBaseURL(baseURL string) Option {
+	return func(api *WealthsimpleAPI) {
+		api.OAuthBaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithGraphQLURL overrides the URL of the GraphQL endpoint
+func WithGraphQLURL(graphQLURL string) Option {
+	return func(api *WealthsimpleAPI) {
+		api.GraphQLURL = graphQLURL
+	}
+}
+
+// WithSubscriptionURL overrides the WebSocket URL of GraphQL subscriptions
+func WithSubscriptionURL(subscriptionURL string) Option {
+	return func(api *WealthsimpleAPI) {
+		api.SubscriptionURL = subscriptionURL
+	}
+}
+
+// WithPersistedQueries enables automatic persisted queries: GraphQL requests carry the sha256 hash of their
+// document, which is only sent in full when the server doesn't know the hash yet
+func WithPersistedQueries() Option {
+	return func(api *WealthsimpleAPI) {
+		api.PersistedQueries = true
+	}
+}
+
+// WithTOTPSecret sets the base32 authenticator seed of the account, used to answer OTP challenges during
+// login with RFC 6238 codes. NewClient fails if the secret can't be decoded
+func WithTOTPSecret(secret string) Option {
+	return func(api *WealthsimpleAPI) {
+		api.totpSecret = secret
+	}
+}
+
+// WithLoginPageURL overrides the page scraped for the device id and the OAuth client id
+func WithLoginPageURL(loginPageURL string) Option {
+	return func(api *WealthsimpleAPI) {
+		api.LoginPageURL = loginPageURL
+	}
+}
+
+// WithAPIVersion sets the x-ws-api-version header sent with GraphQL requests
+func WithAPIVersion(version string) Option {
+	return func(api *WealthsimpleAPI) {
+		api.GraphQLVersion = version
+	}
+}
+
+// WithLocale sets the x-ws-locale header sent with GraphQL requests
+func WithLocale(locale string) Option {
+	return func(api *WealthsimpleAPI) {
+		api.Locale = locale
+	}
+}
+
+// WithProfile sets the x-ws-profile header sent with GraphQL requests
+func WithProfile(profile string) Option {
+	return func(api *WealthsimpleAPI) {
+		api.Profile = profile
+	}
+}
+
+// WithPlatformOS sets the x-platform-os header sent with GraphQL requests
+func WithPlatformOS(platformOS string) Option {
+	return func(api *WealthsimpleAPI) {
+		api.PlatformOS = platformOS
+	}
+}
+
+// WithScopes overrides the OAuth scopes requested for read-only and read-write logins
+func WithScopes(readOnly, readWrite string) Option {
+	return func(api *WealthsimpleAPI) {
+		api.ScopeReadOnly = readOnly
+		api.ScopeReadWrite = readWrite
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(api *WealthsimpleAPI) {
+		api.UserAgent = userAgent
+	}
+}
+
+// WithSecurityMarketDataCache sets the cache functions for security market data
+func WithSecurityMarketDataCache(getter SecurityMarketDataCacheGetter, setter SecurityMarketDataCacheSetter) Option {
+	return func(api *WealthsimpleAPI) {
+		api.SecurityMarketDataCacheGetter = getter
+		api.SecurityMarketDataCacheSetter = setter
+	}
+}
+
+// WithSession resumes an existing session, typically one previously persisted
+func WithSession(sess *WSAPISession) Option {
+	return func(api *WealthsimpleAPI) {
+		if sess != nil {
+			sessCopy := *sess
+			api.Session = &sessCopy
+		}
+	}
+}
+
+// WithPersistSession sets the callback receiving the serialized session every time its tokens change
+func WithPersistSession(persistSessionFct func(string) error) Option {
+	return func(api *WealthsimpleAPI) {
+		api.PersistSession = persistSessionFct
+	}
+}
+
+// WithSessionStore saves the session to store every time its tokens change. FromToken loads the session
+// from store when it isn't given one
+func WithSessionStore(store SessionStore) Option {
+	return func(api *WealthsimpleAPI) {
+		api.SessionStore = store
+	}
+}
+
+// WithMarketDataBatching coalesces concurrent GetSecurityMarketData calls issued within window into a single
+// batched request of up to maxBatch securities
+func WithMarketDataBatching(window time.Duration, maxBatch int) Option {
+	return func(api *WealthsimpleAPI) {
+		api.securityLoader = newSecurityLoader(api, window, maxBatch)
+	}
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+)
+
+// PageInfo describes where a page of a GraphQL connection sits within the whole result set
+type PageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// DefaultCursorVariable is the query variable receiving the cursor of the page to fetch
+const DefaultCursorVariable = "cursor"
+
+// PaginateOpts controls how a connection query is paginated
+type PaginateOpts struct {
+	GraphQlQueryOpts
+	// CursorVariable is the query variable receiving the endCursor of the previous page, defaults to "cursor"
+	CursorVariable string
+	// Limit stops the iteration after that many items, zero means no limit
+	Limit int
+}
+
+// PaginateGraphQLQuery iterates over every node of a GraphQL connection, following pageInfo.endCursor until
+// the connection is exhausted or the limit is reached. DataResponsePath must traverse the connection "edges".
+// Errors are yielded with a zero item. GraphQLErrors reported along with a page are yielded after its nodes and
+// the iteration goes on, any other error ends it
+func PaginateGraphQLQuery[T any](ctx context.Context, api *WealthsimpleAPIBase, opts PaginateOpts) iter.Seq2[T, error] {
+	queryOpts := opts.GraphQlQueryOpts
+	return paginate(queryOpts.QueryName, queryOpts.Variables, opts.CursorVariable, opts.Limit,
+		func(variables map[string]any) ([]T, *PageInfo, error) {
+			queryOpts.Variables = variables
+			result, err := DoGraphQLQueryResult[[]T](ctx, api, queryOpts)
+			if result == nil {
+				return nil, nil, err
+			}
+			return result.Data, result.PageInfo, err
+		})
+}
+
+// OperationPaginateOpts controls how a typed connection operation is paginated
+type OperationPaginateOpts[Data, T any] struct {
+	QueryName string
+	Variables map[string]any
+	// Connection picks the nodes and the pageInfo of the paginated connection out of the operation data,
+	// a nil pageInfo means the connection is missing
+	Connection func(*Data) ([]T, *PageInfo)
+	// CursorVariable is the query variable receiving the endCursor of the previous page, defaults to "cursor"
+	CursorVariable string
+	// Limit stops the iteration after that many items, zero means no limit
+	Limit int
+}
+
+// PaginateGraphQLOperation is the typed counterpart of PaginateGraphQLQuery, every page is decoded into
+// Data in a single pass and the nodes of the selected connection are yielded
+func PaginateGraphQLOperation[Data, T any](ctx context.Context, api *WealthsimpleAPIBase, opts OperationPaginateOpts[Data, T]) iter.Seq2[T, error] {
+	return paginate(opts.QueryName, opts.Variables, opts.CursorVariable, opts.Limit,
+		func(variables map[string]any) ([]T, *PageInfo, error) {
+			data, err := DoGraphQLOperation[Data](ctx, api, opts.QueryName, variables)
+			if data == nil {
+				return nil, nil, err
+			}
+			nodes, pageInfo := opts.Connection(data)
+			if pageInfo == nil {
+				if err == nil {
+					err = fmt.Errorf("%w: connection not found in response of %s", ErrUnexpected, opts.QueryName)
+				}
+				return nil, nil, err
+			}
+			return nodes, pageInfo, err
+		})
+}
+
+// paginate drives fetchPage over every page of a connection
+func paginate[T any](queryName string, initialVariables map[string]any, cursorVariable string, limit int,
+	fetchPage func(variables map[string]any) ([]T, *PageInfo, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		if cursorVariable == "" {
+			cursorVariable = DefaultCursorVariable
+		}
+
+		variables := maps.Clone(initialVariables)
+		if variables == nil {
+			variables = make(map[string]any)
+		}
+
+		count := 0
+		seen := make(map[string]bool)
+		for {
+			page, pageInfo, err := fetchPage(maps.Clone(variables))
+			var gqlErrs GraphQLErrors
+			if err != nil && !errors.As(err, &gqlErrs) {
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+				count++
+				if limit > 0 && count >= limit {
+					break
+				}
+			}
+
+			// The page came with partial data, the caller decides whether the errors end the iteration
+			if err != nil && !yield(zero, err) {
+				return
+			}
+
+			if limit > 0 && count >= limit {
+				return
+			}
+			if pageInfo == nil || !pageInfo.HasNextPage || pageInfo.EndCursor == "" {
+				return
+			}
+			if seen[pageInfo.EndCursor] {
+				yield(zero, fmt.Errorf("%w: cursor %s returned twice by %s", ErrUnexpected, pageInfo.EndCursor, queryName))
+				return
+			}
+			seen[pageInfo.EndCursor] = true
+			variables[cursorVariable] = pageInfo.EndCursor
+		}
+	}
+}
+
+// collectPages drains a paginated query into a slice. The GraphQLErrors of every page are returned together
+// with the items collected, any other error discards them
+func collectPages[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var items []T
+	var gqlErrs GraphQLErrors
+	for item, err := range seq {
+		var pageErrs GraphQLErrors
+		if errors.As(err, &pageErrs) {
+			gqlErrs = append(gqlErrs, pageErrs...)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if len(gqlErrs) > 0 {
+		return items, gqlErrs
+	}
+	return items, nil
+}
+
+// parsePageInfo decodes a pageInfo object, returning nil when absent
+func parsePageInfo(raw any) *PageInfo {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil
+	}
+	pageInfo := &PageInfo{}
+	pageInfo.HasNextPage, _ = m["hasNextPage"].(bool)
+	pageInfo.EndCursor, _ = m["endCursor"].(string)
+	return pageInfo
+}
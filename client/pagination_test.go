@@ -0,0 +1,270 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/vpineda1996/wealthgo/client/graphql/queries"
+)
+
+// activityPage is a page of the activity feed, Next is the cursor of the following page, empty on the last one.
+// Error is reported along with the page when set
+type activityPage struct {
+	IDs   []string
+	Next  string
+	Error string
+}
+
+// newPagedAPI serves pages of FetchActivityFeedItems keyed by the cursor variable, the first page under ""
+// and records the cursors it was asked for
+func newPagedAPI(t *testing.T, pages map[string]activityPage) (*WealthsimpleAPI, func() []string) {
+	f := newFakeAPI(t)
+	var mu sync.Mutex
+	var cursors []string
+	f.handle("FetchActivityFeedItems", func(w http.ResponseWriter, req *fakeRequest) {
+		cursor, _ := req.Variables["cursor"].(string)
+		mu.Lock()
+		cursors = append(cursors, cursor)
+		mu.Unlock()
+
+		page, ok := pages[cursor]
+		if !ok {
+			writeJSON(w, http.StatusOK, `{"data":null,"errors":[{"message":"invalid cursor"}]}`)
+			return
+		}
+		edges := make([]any, len(page.IDs))
+		for i, id := range page.IDs {
+			edges[i] = map[string]any{"node": map[string]any{"canonicalId": id, "accountId": "tfsa-1", "amount": "1"}}
+		}
+		var endCursor any
+		if page.Next != "" {
+			endCursor = page.Next
+		}
+		response := map[string]any{"data": map[string]any{"activityFeedItems": map[string]any{
+			"edges":    edges,
+			"pageInfo": map[string]any{"hasNextPage": page.Next != "", "endCursor": endCursor},
+		}}}
+		if page.Error != "" {
+			response["errors"] = []any{map[string]any{"message": page.Error}}
+		}
+		body, _ := json.Marshal(response)
+		writeJSON(w, http.StatusOK, string(body))
+	})
+	return f.newClient(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(cursors)
+	}
+}
+
+var threePages = map[string]activityPage{
+	"":   {IDs: []string{"a1", "a2"}, Next: "c1"},
+	"c1": {IDs: []string{"a3", "a4"}, Next: "c2"},
+	"c2": {IDs: []string{"a5"}},
+}
+
+func paginateActivities(api *WealthsimpleAPI, variables map[string]any, limit int) ([]string, error) {
+	var ids []string
+	seq := PaginateGraphQLQuery[queries.Activity](context.Background(), &api.WealthsimpleAPIBase, PaginateOpts{
+		GraphQlQueryOpts: GraphQlQueryOpts{
+			QueryName:        "FetchActivityFeedItems",
+			Variables:        variables,
+			DataResponsePath: "activityFeedItems.edges",
+			ExpectType:       ArrayType,
+		},
+		Limit: limit,
+	})
+	for activity, err := range seq {
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, *activity.CanonicalId)
+	}
+	return ids, nil
+}
+
+func TestPaginateGraphQLQuery(t *testing.T) {
+	api, cursors := newPagedAPI(t, threePages)
+	variables := map[string]any{"first": 2}
+
+	ids, err := paginateActivities(api, variables, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a1", "a2", "a3", "a4", "a5"}; !slices.Equal(ids, want) {
+		t.Errorf("got %v, want %v", ids, want)
+	}
+	if want := []string{"", "c1", "c2"}; !slices.Equal(cursors(), want) {
+		t.Errorf("requested cursors %v, want %v", cursors(), want)
+	}
+	if _, ok := variables["cursor"]; ok {
+		t.Error("the caller's variables were modified")
+	}
+}
+
+func TestPaginateLimit(t *testing.T) {
+	api, cursors := newPagedAPI(t, threePages)
+
+	ids, err := paginateActivities(api, nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a1", "a2", "a3"}; !slices.Equal(ids, want) {
+		t.Errorf("got %v, want %v", ids, want)
+	}
+	if got := len(cursors()); got != 2 {
+		t.Errorf("fetched %d pages, want 2", got)
+	}
+}
+
+func TestPaginateBreak(t *testing.T) {
+	api, cursors := newPagedAPI(t, threePages)
+
+	seq := PaginateGraphQLQuery[queries.Activity](context.Background(), &api.WealthsimpleAPIBase, PaginateOpts{
+		GraphQlQueryOpts: GraphQlQueryOpts{
+			QueryName:        "FetchActivityFeedItems",
+			Variables:        map[string]any{},
+			DataResponsePath: "activityFeedItems.edges",
+			ExpectType:       ArrayType,
+		},
+	})
+	for _, err := range seq {
+		if err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+	if got := len(cursors()); got != 1 {
+		t.Errorf("fetched %d pages after the loop ended, want 1", got)
+	}
+}
+
+func TestPaginateCursorLoop(t *testing.T) {
+	api, cursors := newPagedAPI(t, map[string]activityPage{
+		"":   {IDs: []string{"a1"}, Next: "c1"},
+		"c1": {IDs: []string{"a2"}, Next: "c2"},
+		"c2": {IDs: []string{"a3"}, Next: "c1"},
+	})
+
+	ids, err := paginateActivities(api, nil, 0)
+	if !errors.Is(err, ErrUnexpected) {
+		t.Fatalf("err = %v, want ErrUnexpected", err)
+	}
+	if want := []string{"a1", "a2", "a3"}; !slices.Equal(ids, want) {
+		t.Errorf("got %v before the loop was detected, want %v", ids, want)
+	}
+	if got := len(cursors()); got != 3 {
+		t.Errorf("fetched %d pages, want 3", got)
+	}
+}
+
+func TestPaginateError(t *testing.T) {
+	api, _ := newPagedAPI(t, map[string]activityPage{
+		"": {IDs: []string{"a1"}, Next: "expired"},
+	})
+
+	ids, err := paginateActivities(api, nil, 0)
+	if err == nil {
+		t.Fatal("the failed page was ignored")
+	}
+	if !slices.Equal(ids, []string{"a1"}) {
+		t.Errorf("got %v, want the first page", ids)
+	}
+}
+
+func TestPaginateGraphQLOperation(t *testing.T) {
+	pages := make(map[string]activityPage)
+	cursor := ""
+	for i := range 5 {
+		page := activityPage{IDs: []string{fmt.Sprintf("a%d", 2*i), fmt.Sprintf("a%d", 2*i+1)}}
+		if i < 4 {
+			page.Next = fmt.Sprintf("c%d", i+1)
+		}
+		pages[cursor] = page
+		cursor = page.Next
+	}
+	api, cursors := newPagedAPI(t, pages)
+
+	activities, err := api.GetActivitiesWithContext(context.Background(), "tfsa-1", 7, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 7 || *activities[6].CanonicalId != "a6" {
+		t.Errorf("got %d activities, want a0 to a6", len(activities))
+	}
+	if got := len(cursors()); got != 4 {
+		t.Errorf("fetched %d pages, want 4", got)
+	}
+}
+
+func TestPaginatePartialData(t *testing.T) {
+	api, cursors := newPagedAPI(t, map[string]activityPage{
+		"":   {IDs: []string{"a1", "a2"}, Next: "c1"},
+		"c1": {IDs: []string{"a3"}, Next: "c2", Error: "amount unavailable"},
+		"c2": {IDs: []string{"a4"}, Error: "status unavailable"},
+	})
+
+	// The nodes of a page come before its errors and the following pages are still fetched
+	var events []string
+	seq := PaginateGraphQLQuery[queries.Activity](context.Background(), &api.WealthsimpleAPIBase, PaginateOpts{
+		GraphQlQueryOpts: GraphQlQueryOpts{
+			QueryName:        "FetchActivityFeedItems",
+			Variables:        map[string]any{},
+			DataResponsePath: "activityFeedItems.edges",
+			ExpectType:       ArrayType,
+		},
+	})
+	for activity, err := range seq {
+		var gqlErrs GraphQLErrors
+		switch {
+		case errors.As(err, &gqlErrs):
+			events = append(events, "error: "+gqlErrs[0].Message)
+		case err != nil:
+			t.Fatal(err)
+		default:
+			events = append(events, *activity.CanonicalId)
+		}
+	}
+	want := []string{"a1", "a2", "a3", "error: amount unavailable", "a4", "error: status unavailable"}
+	if !slices.Equal(events, want) {
+		t.Errorf("got %q, want %q", events, want)
+	}
+
+	// The getters return every node collected along with the errors of every page
+	activities, err := api.GetActivitiesWithContext(context.Background(), "tfsa-1", 10, "", false)
+	var gqlErrs GraphQLErrors
+	if !errors.As(err, &gqlErrs) || len(gqlErrs) != 2 {
+		t.Fatalf("err = %v, want the GraphQL errors of both pages", err)
+	}
+	if len(activities) != 4 {
+		t.Errorf("got %d activities, want 4", len(activities))
+	}
+	if got := len(cursors()); got != 6 {
+		t.Errorf("fetched %d pages, want 6", got)
+	}
+}
+
+func TestPaginatePartialDataLimit(t *testing.T) {
+	api, cursors := newPagedAPI(t, map[string]activityPage{
+		"":   {IDs: []string{"a1", "a2"}, Next: "c1", Error: "amount unavailable"},
+		"c1": {IDs: []string{"a3"}},
+	})
+
+	// The errors of the last page are reported even though the limit was reached within it
+	ids, err := paginateActivities(api, nil, 1)
+	if !errors.As(err, new(GraphQLErrors)) {
+		t.Fatalf("err = %v, want GraphQLErrors", err)
+	}
+	if !slices.Equal(ids, []string{"a1"}) {
+		t.Errorf("got %v, want a1", ids)
+	}
+	if got := len(cursors()); got != 1 {
+		t.Errorf("fetched %d pages, want 1", got)
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"maps"
+)
+
+// persistedQueryVersion is the version of the automatic persisted queries protocol
+const persistedQueryVersion = 1
+
+// Errors reported by servers implementing automatic persisted queries, either as message or extensions.code
+const (
+	persistedQueryNotFound         = "PersistedQueryNotFound"
+	persistedQueryNotFoundCode     = "PERSISTED_QUERY_NOT_FOUND"
+	persistedQueryNotSupported     = "PersistedQueryNotSupported"
+	persistedQueryNotSupportedCode = "PERSISTED_QUERY_NOT_SUPPORTED"
+)
+
+// embeddedQueryHashes holds the hash of every embedded query, keyed by document
+var embeddedQueryHashes = hashQueries(embeddedQueries)
+
+// hashQueries computes the hash of every document of queries
+func hashQueries(queries map[string]string) map[string]string {
+	hashes := make(map[string]string, len(queries))
+	for _, document := range queries {
+		hashes[document] = queryHash(document)
+	}
+	return hashes
+}
+
+// queryHash returns the hex encoded sha256 identifying document
+func queryHash(document string) string {
+	sum := sha256.Sum256([]byte(document))
+	return hex.EncodeToString(sum[:])
+}
+
+// persistedQueryHash returns the hash of document, precomputed for the embedded and registered queries
+func (api *WealthsimpleAPIBase) persistedQueryHash(document string) string {
+	if hash, ok := embeddedQueryHashes[document]; ok {
+		return hash
+	}
+	api.queriesMu.RLock()
+	hash, ok := api.queryHashes[document]
+	api.queriesMu.RUnlock()
+	if ok {
+		return hash
+	}
+	return queryHash(document)
+}
+
+// usePersistedQueries reports whether GraphQL documents should be sent by hash first
+func (api *WealthsimpleAPIBase) usePersistedQueries() bool {
+	return api.PersistedQueries && !api.persistedQueriesUnsupported.Load()
+}
+
+// persistedQueryRequests returns the request sending the hash of query alone, and the one sending the
+// document along with its hash, for servers that don't know the hash yet
+func (api *WealthsimpleAPIBase) persistedQueryRequests(query map[string]any) (hashed, full map[string]any) {
+	document, _ := query["query"].(string)
+	extensions := map[string]any{
+		"persistedQuery": map[string]any{
+			"version":    persistedQueryVersion,
+			"sha256Hash": api.persistedQueryHash(document),
+		},
+	}
+
+	hashed = maps.Clone(query)
+	delete(hashed, "query")
+	hashed["extensions"] = extensions
+
+	full = maps.Clone(query)
+	full["extensions"] = extensions
+	return hashed, full
+}
+
+// isPersistedQueryError reports whether err carries the persisted query error named message or code
+func isPersistedQueryError(err error, message, code string) bool {
+	var gqlErrs GraphQLErrors
+	if !errors.As(err, &gqlErrs) {
+		return false
+	}
+	for _, e := range gqlErrs {
+		if e.Message == message || e.Code() == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// apqRequest is what the stand-in received for an operation
+type apqRequest struct {
+	hash     string
+	document string
+}
+
+// apqServer is an APQ aware FetchBalance handler remembering the documents sent along with their hash
+type apqServer struct {
+	mu       sync.Mutex
+	stored   map[string]string
+	requests []apqRequest
+}
+
+func (s *apqServer) serve(w http.ResponseWriter, req *fakeRequest) {
+	var hash string
+	if persisted, ok := req.Extensions["persistedQuery"].(map[string]any); ok {
+		hash, _ = persisted["sha256Hash"].(string)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, apqRequest{hash: hash, document: req.Query})
+
+	switch {
+	case hash == "":
+	case req.Query != "":
+		sum := sha256.Sum256([]byte(req.Query))
+		if hex.EncodeToString(sum[:]) != hash {
+			writeJSON(w, http.StatusBadRequest, `{"errors":[{"message":"provided sha does not match query"}]}`)
+			return
+		}
+		s.stored[hash] = req.Query
+	case s.stored[hash] == "":
+		writeJSON(w, http.StatusOK, `{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`)
+		return
+	}
+	writeJSON(w, http.StatusOK, balanceResponse)
+}
+
+// take returns and forgets the requests received so far
+func (s *apqServer) take() []apqRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+func newAPQAPI(t *testing.T, opts ...Option) (*apqServer, *WealthsimpleAPI) {
+	f, api := newBalanceAPI(t, opts...)
+	s := &apqServer{stored: make(map[string]string)}
+	f.handle("FetchBalance", s.serve)
+	return s, api
+}
+
+func TestPersistedQueries(t *testing.T) {
+	s, api := newAPQAPI(t, WithPersistedQueries())
+
+	// The first request only sends the hash, the server asks for the document
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+	requests := s.take()
+	if len(requests) != 2 || requests[0].document != "" || requests[1].document == "" {
+		t.Fatalf("got requests %+v, want the hash alone then the document", requests)
+	}
+	if requests[0].hash == "" || requests[0].hash != requests[1].hash {
+		t.Errorf("hashes %q and %q differ", requests[0].hash, requests[1].hash)
+	}
+
+	// The server now knows the hash
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+	if requests := s.take(); len(requests) != 1 || requests[0].document != "" {
+		t.Errorf("got requests %+v, want the hash alone", requests)
+	}
+}
+
+func TestPersistedQueriesNotSupported(t *testing.T) {
+	f, api := newBalanceAPI(t, WithPersistedQueries())
+	var documents []string
+	f.handle("FetchBalance", func(w http.ResponseWriter, req *fakeRequest) {
+		documents = append(documents, req.Query)
+		if req.Extensions != nil {
+			writeJSON(w, http.StatusBadRequest, `{"errors":[{"message":"PersistedQueryNotSupported"}]}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, balanceResponse)
+	})
+
+	for range 2 {
+		if _, err := fetchBalance(api); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The hash is only tried once, every request sends the document afterwards
+	if len(documents) != 3 || documents[0] != "" || documents[1] == "" || documents[2] == "" {
+		t.Errorf("got documents %q, want the hash alone then the document twice", documents)
+	}
+}
+
+func TestPersistedQueriesDisabled(t *testing.T) {
+	s, api := newAPQAPI(t)
+
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+	if requests := s.take(); len(requests) != 1 || requests[0].hash != "" || requests[0].document == "" {
+		t.Errorf("got requests %+v, want the document without hash", requests)
+	}
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// operationNameRe matches the first named operation definition of a GraphQL document
+var operationNameRe = regexp.MustCompile(`(?m)^\s*(?:query|mutation|subscription)\s+(\w+)`)
+
+// operationName returns the name of the first operation defined in document, or "" if it is anonymous
+func operationName(document string) string {
+	if m := operationNameRe.FindStringSubmatch(document); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// readQueries reads every .graphql file of dir, keyed by file name without the extension
+func readQueries(fsys fs.FS, dir string) (map[string]string, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make(map[string]string)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".graphql") {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", file.Name(), err)
+		}
+		queries[strings.TrimSuffix(file.Name(), ".graphql")] = string(content)
+	}
+	return queries, nil
+}
+
+// RegisterQuery adds the GraphQL operation name to the client, replacing any query already registered under
+// that name, embedded ones included. document must define an operation called name, fragments it spreads
+// must be part of the same document
+func (api *WealthsimpleAPIBase) RegisterQuery(name, document string) error {
+	defined := regexp.MustCompile(`\b(?:query|mutation|subscription)\s+` + regexp.QuoteMeta(name) + `\b`)
+	if name == "" || !defined.MatchString(document) {
+		return fmt.Errorf("%w: document doesn't define an operation named %q", ErrUnexpected, name)
+	}
+
+	api.queriesMu.Lock()
+	defer api.queriesMu.Unlock()
+	if api.GraphQLQueries == nil {
+		api.GraphQLQueries = make(map[string]string)
+	}
+	api.GraphQLQueries[name] = document
+	if _, ok := embeddedQueryHashes[document]; !ok {
+		if api.queryHashes == nil {
+			api.queryHashes = make(map[string]string)
+		}
+		api.queryHashes[document] = queryHash(document)
+	}
+	return nil
+}
+
+// RegisterQueriesFS registers every .graphql file of dir in fsys, each named after its file like the
+// embedded queries. Nothing is registered if any of the files is invalid
+func (api *WealthsimpleAPIBase) RegisterQueriesFS(fsys fs.FS, dir string) error {
+	queries, err := readQueries(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnexpected, err)
+	}
+	for name, document := range queries {
+		if operationName(document) != name {
+			return fmt.Errorf("%w: %s.graphql doesn't define an operation named %s", ErrUnexpected, name, name)
+		}
+	}
+
+	for name, document := range queries {
+		if err := api.RegisterQuery(name, document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query returns the document registered under name
+func (api *WealthsimpleAPIBase) Query(name string) (string, bool) {
+	api.queriesMu.RLock()
+	defer api.queriesMu.RUnlock()
+	document, ok := api.GraphQLQueries[name]
+	return document, ok
+}
+
+// RawQuery sends an ad-hoc GraphQL document, which doesn't need to be registered, and returns the raw "data"
+// member. Auth headers, retries and errors are handled like for DoGraphQLQuery
+func (api *WealthsimpleAPIBase) RawQuery(ctx context.Context, document string, variables map[string]any) (map[string]any, error) {
+	data, err := RawQueryInto[map[string]any](ctx, api, document, variables)
+	if data == nil {
+		return nil, err
+	}
+	return *data, err
+}
+
+// RawQueryInto is like RawQuery but decodes the "data" member into Data
+func RawQueryInto[Data any](ctx context.Context, api *WealthsimpleAPIBase, document string, variables map[string]any) (*Data, error) {
+	return runOperation[Data](ctx, api, operationName(document), document, variables)
+}
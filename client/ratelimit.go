@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter throttles outgoing requests, implementations must be safe for concurrent use
+type RateLimiter interface {
+	// Wait blocks until a request may be sent or ctx is done
+	Wait(ctx context.Context) error
+}
+
+// TokenBucket is a RateLimiter allowing bursts of up to burst requests and refilling at a steady rate
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a token bucket that allows requestsPerSecond on average with bursts of up to burst requests
+func NewTokenBucket(requestsPerSecond float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes a token from the bucket, blocking until one is available or ctx is done
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	delay := b.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes a token, possibly borrowing from the future, and returns how long the caller must wait for it
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel gives back a token reserved by a caller that stopped waiting
+func (b *TokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// rateLimiterFor returns the limiter guarding the endpoint url belongs to, if any. The endpoints may share a
+// server, the longest matching base URL names the endpoint
+func (api *WealthsimpleAPIBase) rateLimiterFor(url string) RateLimiter {
+	oauth := strings.HasPrefix(url, api.OAuthBaseURL)
+	graphQL := strings.HasPrefix(url, api.GraphQLURL)
+	switch {
+	case graphQL && (!oauth || len(api.GraphQLURL) >= len(api.OAuthBaseURL)):
+		return api.GraphQLRateLimiter
+	case oauth:
+		return api.OAuthRateLimiter
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	ctx := context.Background()
+	bucket := NewTokenBucket(10, 3)
+
+	start := time.Now()
+	for range 3 {
+		if err := bucket.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("burst took %v, want no wait", elapsed)
+	}
+
+	// The bucket is empty, the next token comes after 1/rate
+	if err := bucket.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("request past the burst waited %v, want about 100ms", elapsed)
+	}
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	ctx := context.Background()
+	bucket := NewTokenBucket(100, 1)
+	if err := bucket.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	start := time.Now()
+	if err := bucket.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+		t.Errorf("refilled bucket waited %v", elapsed)
+	}
+}
+
+func TestTokenBucketContextDone(t *testing.T) {
+	bucket := NewTokenBucket(1, 1)
+	if err := bucket.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bucket.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+
+	// The abandoned reservation was given back, the next token is due a second after the first one
+	// rather than two
+	if delay := bucket.reserve(); delay > time.Second {
+		t.Errorf("next token in %v, want at most 1s", delay)
+	}
+}
+
+func TestTokenBucketUnlimited(t *testing.T) {
+	bucket := NewTokenBucket(0, 1)
+	for range 100 {
+		if delay := bucket.reserve(); delay != 0 {
+			t.Fatalf("zero rate waited %v", delay)
+		}
+	}
+}
+
+// countingLimiter records the requests it lets through
+type countingLimiter struct {
+	waits atomic.Int32
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits.Add(1)
+	return nil
+}
+
+func TestGraphQLRateLimiter(t *testing.T) {
+	f := newFakeAPI(t)
+	limiter := &countingLimiter{}
+	f.respond("FetchBalance", balanceResponse)
+	api := f.newClient(WithGraphQLRateLimiter(limiter))
+	if err := api.RegisterQuery("FetchBalance", `query FetchBalance { balance { amount cents currency } }`); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		if _, err := fetchBalance(api); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := limiter.waits.Load(); got != 3 {
+		t.Errorf("limiter saw %d requests, want 3", got)
+	}
+}
+
+func TestRateLimitersSharingServer(t *testing.T) {
+	// The fake API serves OAuth at the root of the server and GraphQL under /graphql
+	f := newFakeAPI(t)
+	f.respond("FetchBalance", balanceResponse)
+	oauth, graphQL := &countingLimiter{}, &countingLimiter{}
+	api := f.newClient(WithOAuthRateLimiter(oauth), WithGraphQLRateLimiter(graphQL))
+	if err := api.RegisterQuery("FetchBalance", `query FetchBalance { balance { amount cents currency } }`); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := fetchBalance(api); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := api.refreshAccessToken(context.Background(), "access-0"); err != nil {
+		t.Fatal(err)
+	}
+	if got := graphQL.waits.Load(); got != 2 {
+		t.Errorf("GraphQL limiter saw %d requests, want 2", got)
+	}
+	if got := oauth.waits.Load(); got != 1 {
+		t.Errorf("OAuth limiter saw %d requests, want 1", got)
+	}
+
+	// GraphQL requests aren't charged to the OAuth limiter when they have none of their own
+	oauthOnly := f.newClient(WithOAuthRateLimiter(oauth))
+	if limiter := oauthOnly.rateLimiterFor(oauthOnly.GraphQLURL); limiter != nil {
+		t.Errorf("GraphQL requests limited by %v", limiter)
+	}
+}
+
+func TestRateLimiterCancelsRequest(t *testing.T) {
+	f := newFakeAPI(t)
+	f.respond("FetchBalance", balanceResponse)
+	api := f.newClient(WithGraphQLRateLimiter(NewTokenBucket(0.001, 1)))
+	if err := api.RegisterQuery("FetchBalance", `query FetchBalance { balance { amount cents currency } }`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := DoGraphQLQueryWithContext[map[string]any](ctx, &api.WealthsimpleAPIBase, GraphQlQueryOpts{
+		QueryName:        "FetchBalance",
+		Variables:        map[string]any{},
+		DataResponsePath: "balance",
+		ExpectType:       ObjectType,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+	if got := f.graphQLRequests.Load(); got != 1 {
+		t.Errorf("sent %d requests, want the throttled one held back", got)
+	}
+}
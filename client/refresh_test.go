@@ -0,0 +1,95 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRefreshOnUnauthorized(t *testing.T) {
+	var persisted []string
+	f, api := newBalanceAPI(t, WithPersistSession(func(session string) error {
+		persisted = append(persisted, session)
+		return nil
+	}))
+	f.accessToken = "revoked"
+
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.tokenRequests.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want 1", got)
+	}
+	// The rejected request and its replay
+	if got := f.graphQLRequests.Load(); got != 2 {
+		t.Errorf("sent %d GraphQL requests, want 2", got)
+	}
+
+	accessToken, refreshToken := f.tokens()
+	session := api.CurrentSession()
+	if session.AccessToken != accessToken || session.RefreshToken != refreshToken {
+		t.Errorf("session holds %s/%s, want %s/%s", session.AccessToken, session.RefreshToken, accessToken, refreshToken)
+	}
+	if len(persisted) != 1 {
+		t.Fatalf("session persisted %d times, want 1", len(persisted))
+	}
+	var saved WSAPISession
+	if err := json.Unmarshal([]byte(persisted[0]), &saved); err != nil || saved.RefreshToken != refreshToken {
+		t.Errorf("persisted %s (%v), want refresh token %s", persisted[0], err, refreshToken)
+	}
+}
+
+func TestRefreshOnceWhenStillUnauthorized(t *testing.T) {
+	f, api := newBalanceAPI(t)
+	var requests atomic.Int32
+	f.handle("FetchBalance", func(w http.ResponseWriter, req *fakeRequest) {
+		requests.Add(1)
+		writeJSON(w, http.StatusForbidden, `{"message":"Not Authorized."}`)
+	})
+
+	if _, err := fetchBalance(api); !errors.Is(err, ErrNotAuthorized) {
+		t.Fatalf("err = %v, want ErrNotAuthorized", err)
+	}
+	if got := f.tokenRequests.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want 1", got)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("sent %d requests, want the request and a single replay", got)
+	}
+}
+
+func TestRefreshFailureIsReturned(t *testing.T) {
+	f, api := newBalanceAPI(t, WithSession(&WSAPISession{AccessToken: "access-0", RefreshToken: "revoked"}))
+	f.accessToken = "revoked"
+
+	if _, err := fetchBalance(api); !errors.Is(err, ErrManualLogin) {
+		t.Fatalf("err = %v, want ErrManualLogin", err)
+	}
+	if got := f.graphQLRequests.Load(); got != 1 {
+		t.Errorf("sent %d GraphQL requests, want no replay", got)
+	}
+}
+
+func TestRefreshSingleFlight(t *testing.T) {
+	f, api := newBalanceAPI(t)
+	f.accessToken = "revoked"
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := fetchBalance(api); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := f.tokenRequests.Load(); got != 1 {
+		t.Errorf("token refreshed %d times by concurrent requests, want 1", got)
+	}
+}
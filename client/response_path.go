@@ -0,0 +1,224 @@
+package client
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ResponseExtractor picks the value to decode out of the "data" member of a GraphQL response, along with
+// the pageInfo of every connection it went through, in traversal order
+type ResponseExtractor interface {
+	Extract(data any) (any, []PageInfoAt, error)
+}
+
+// ExtractorFunc adapts a function to the ResponseExtractor interface
+type ExtractorFunc func(data any) (any, []PageInfoAt, error)
+
+// Extract calls f
+func (f ExtractorFunc) Extract(data any) (any, []PageInfoAt, error) {
+	return f(data)
+}
+
+// PageInfoAt is the pageInfo of the connection found at Path
+type PageInfoAt struct {
+	Path     string
+	PageInfo *PageInfo
+}
+
+// pathStep is a single navigation step of a ResponsePath
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// ResponsePath is a parsed DataResponsePath. Segments are separated by dots and address object members,
+// optionally followed by list indices:
+//
+//	identity.accounts.edges[0].custodianAccounts[-1].id
+//
+// Negative indices count from the end. A "*" segment or a "[*]" index matches every member of an object or
+// element of a list, the result is then the list of every value matched. An "edges" segment is replaced by
+// the nodes of the connection and records its pageInfo, at any depth
+type ResponsePath struct {
+	raw   string
+	steps []pathStep
+}
+
+// ParseResponsePath parses a DataResponsePath
+func ParseResponsePath(path string) (ResponsePath, error) {
+	p := ResponsePath{raw: path}
+	if path == "" {
+		return p, fmt.Errorf("%w: empty response path", ErrUnexpected)
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		key, indices, hasIndices := strings.Cut(segment, "[")
+		switch {
+		case key == "":
+			return p, fmt.Errorf("%w: empty segment in response path %q", ErrUnexpected, path)
+		case strings.Contains(key, "]"):
+			return p, fmt.Errorf("%w: malformed index in response path %q", ErrUnexpected, path)
+		case key == "*":
+			p.steps = append(p.steps, pathStep{wildcard: true})
+		default:
+			p.steps = append(p.steps, pathStep{key: key})
+		}
+
+		for rest := "[" + indices; hasIndices && rest != ""; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return p, fmt.Errorf("%w: malformed index in response path %q", ErrUnexpected, path)
+			}
+			index := rest[1:end]
+			rest = rest[end+1:]
+
+			if index == "*" {
+				p.steps = append(p.steps, pathStep{wildcard: true})
+				continue
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil {
+				return p, fmt.Errorf("%w: invalid index %q in response path %q", ErrUnexpected, index, path)
+			}
+			p.steps = append(p.steps, pathStep{index: i, isIndex: true})
+		}
+	}
+	return p, nil
+}
+
+// String returns the path as it was parsed
+func (p ResponsePath) String() string {
+	return p.raw
+}
+
+// pathMatch is a value reached while following a path, and where it was found
+type pathMatch struct {
+	path  string
+	value any
+}
+
+// Extract follows the path through data. The value found is returned as is, unless a wildcard was
+// traversed, in which case every value matched is returned in a list. Null values matched by a
+// wildcard are skipped, a missing value is an error otherwise
+func (p ResponsePath) Extract(data any) (any, []PageInfoAt, error) {
+	matches := []pathMatch{{value: data}}
+	fanOut := false
+	var pageInfos []PageInfoAt
+
+	for _, step := range p.steps {
+		var next []pathMatch
+		for _, m := range matches {
+			switch {
+			case step.wildcard:
+				switch value := m.value.(type) {
+				case []any:
+					for i, item := range value {
+						next = append(next, pathMatch{path: fmt.Sprintf("%s[%d]", m.path, i), value: item})
+					}
+				case map[string]any:
+					for _, key := range slices.Sorted(maps.Keys(value)) {
+						next = append(next, pathMatch{path: joinPath(m.path, key), value: value[key]})
+					}
+				default:
+					return nil, nil, fmt.Errorf("%w: %s is not a list or an object", ErrUnexpected, displayPath(m.path))
+				}
+
+			case step.isIndex:
+				list, ok := m.value.([]any)
+				if !ok {
+					return nil, nil, fmt.Errorf("%w: %s is not a list", ErrUnexpected, displayPath(m.path))
+				}
+				i := step.index
+				if i < 0 {
+					i += len(list)
+				}
+				if i < 0 || i >= len(list) {
+					if fanOut {
+						continue
+					}
+					return nil, nil, fmt.Errorf("%w: index %d out of range for %s of length %d", ErrUnexpected, step.index, displayPath(m.path), len(list))
+				}
+				next = append(next, pathMatch{path: fmt.Sprintf("%s[%d]", m.path, i), value: list[i]})
+
+			default:
+				object, ok := m.value.(map[string]any)
+				if !ok {
+					return nil, nil, fmt.Errorf("%w: %s is not an object", ErrUnexpected, displayPath(m.path))
+				}
+				path := joinPath(m.path, step.key)
+				value := object[step.key]
+				if value != nil && step.key == "edges" {
+					nodes, err := edgeNodes(path, value)
+					if err != nil {
+						return nil, nil, err
+					}
+					pageInfos = append(pageInfos, PageInfoAt{Path: displayPath(m.path), PageInfo: parsePageInfo(object["pageInfo"])})
+					value = nodes
+				}
+				next = append(next, pathMatch{path: path, value: value})
+			}
+		}
+
+		if step.wildcard {
+			fanOut = true
+		}
+		matches = next[:0]
+		for _, m := range next {
+			if m.value != nil {
+				matches = append(matches, m)
+			} else if !fanOut {
+				return nil, nil, fmt.Errorf("%w: path %s not found in response", ErrUnexpected, m.path)
+			}
+		}
+	}
+
+	if !fanOut {
+		return matches[0].value, pageInfos, nil
+	}
+	values := make([]any, len(matches))
+	for i, m := range matches {
+		values[i] = m.value
+	}
+	return values, pageInfos, nil
+}
+
+// edgeNodes replaces the edges of a connection by their nodes
+func edgeNodes(path string, value any) ([]any, error) {
+	edges, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a list", ErrUnexpected, path)
+	}
+	nodes := make([]any, len(edges))
+	for i, edge := range edges {
+		edgeMap, ok := edge.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s[%d] is not an object", ErrUnexpected, path, i)
+		}
+		node, ok := edgeMap["node"]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s[%d] has no node", ErrUnexpected, path, i)
+		}
+		nodes[i] = node
+	}
+	return nodes, nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// displayPath names the root of the data when path is empty
+func displayPath(path string) string {
+	if path == "" {
+		return "data"
+	}
+	return path
+}
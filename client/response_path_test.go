@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const responsePathData = `{
+	"identity": {
+		"id": "identity-1",
+		"accounts": {
+			"pageInfo": {"hasNextPage": true, "endCursor": "c1"},
+			"edges": [
+				{"node": {"id": "tfsa-1", "custodianAccounts": [{"id": "ca-1"}, {"id": "ca-2"}], "nickname": "Travel"}},
+				{"node": {"id": "rrsp-1", "custodianAccounts": [{"id": "ca-3"}], "nickname": null}}
+			]
+		},
+		"balances": {"cad": {"amount": "1.00"}, "usd": {"amount": "2.00"}, "eur": null}
+	}
+}`
+
+func decodeData(t *testing.T, raw string) any {
+	t.Helper()
+	var data any
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseResponsePathRejects(t *testing.T) {
+	for _, path := range []string{"", "identity..id", ".identity", "accounts[0", "accounts[x]", "accounts[0]x", "accounts]"} {
+		if _, err := ParseResponsePath(path); !errors.Is(err, ErrUnexpected) {
+			t.Errorf("ParseResponsePath(%q) err = %v, want ErrUnexpected", path, err)
+		}
+	}
+}
+
+func TestResponsePathExtract(t *testing.T) {
+	data := decodeData(t, responsePathData)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"identity.id", `"identity-1"`},
+		{"identity.accounts.edges[0].id", `"tfsa-1"`},
+		{"identity.accounts.edges[-1].id", `"rrsp-1"`},
+		{"identity.accounts.edges[0].custodianAccounts[-1].id", `"ca-2"`},
+		{"identity.accounts.edges[*].id", `["tfsa-1","rrsp-1"]`},
+		{"identity.accounts.edges[*].custodianAccounts[*].id", `["ca-1","ca-2","ca-3"]`},
+		{"identity.accounts.edges[*].custodianAccounts[1].id", `["ca-2"]`},
+		{"identity.accounts.edges[*].nickname", `["Travel"]`},
+		{"identity.balances.*.amount", `["1.00","2.00"]`},
+		{"identity.accounts.pageInfo", `{"endCursor":"c1","hasNextPage":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := ParseResponsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, _, err := path.Extract(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(value)
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponsePathExtractErrors(t *testing.T) {
+	data := decodeData(t, responsePathData)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"identity.email", "path identity.email not found"},
+		{"identity.accounts.edges[0].nickname.first", "not an object"},
+		{"identity.accounts.edges[5]", "index 5 out of range"},
+		{"identity.accounts.edges[-3]", "index -3 out of range"},
+		{"identity.id[0]", "identity.id is not a list"},
+		{"identity.id.*", "not a list or an object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := ParseResponsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = path.Extract(data)
+			if !errors.Is(err, ErrUnexpected) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want ErrUnexpected containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponsePathPageInfos(t *testing.T) {
+	data := decodeData(t, `{"identity": {"accounts": {
+		"pageInfo": {"hasNextPage": false, "endCursor": "a1"},
+		"edges": [
+			{"node": {"activities": {"pageInfo": {"hasNextPage": true, "endCursor": "x1"}, "edges": [{"node": {"id": "x"}}]}}},
+			{"node": {"activities": {"pageInfo": {"hasNextPage": false, "endCursor": "y1"}, "edges": [{"node": {"id": "y"}}]}}}
+		]
+	}}}`)
+	path, err := ParseResponsePath("identity.accounts.edges[*].activities.edges")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, pageInfos, err := path.Extract(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PageInfoAt{
+		{Path: "identity.accounts", PageInfo: &PageInfo{EndCursor: "a1"}},
+		{Path: "identity.accounts.edges[0].activities", PageInfo: &PageInfo{HasNextPage: true, EndCursor: "x1"}},
+		{Path: "identity.accounts.edges[1].activities", PageInfo: &PageInfo{EndCursor: "y1"}},
+	}
+	if !reflect.DeepEqual(pageInfos, want) {
+		t.Errorf("got %+v, want %+v", pageInfos, want)
+	}
+}
+
+func TestDoGraphQLQueryResultPageInfo(t *testing.T) {
+	f, api := newBalanceAPI(t)
+	f.respond("FetchBalance", `{"data":`+responsePathData+`}`)
+
+	result, err := DoGraphQLQueryResult[[]string](context.Background(), &api.WealthsimpleAPIBase, GraphQlQueryOpts{
+		QueryName:        "FetchBalance",
+		Variables:        map[string]any{},
+		DataResponsePath: "identity.accounts.edges[*].id",
+		ExpectType:       reflect.TypeOf([]any{}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Data, []string{"tfsa-1", "rrsp-1"}) {
+		t.Errorf("Data = %v", result.Data)
+	}
+	if result.PageInfo == nil || *result.PageInfo != (PageInfo{HasNextPage: true, EndCursor: "c1"}) {
+		t.Errorf("PageInfo = %+v", result.PageInfo)
+	}
+	if result.PageInfos["identity.accounts"] != result.PageInfo {
+		t.Errorf("PageInfos = %+v", result.PageInfos)
+	}
+}
+
+func TestDoGraphQLQueryExtractor(t *testing.T) {
+	f, api := newBalanceAPI(t)
+	f.respond("FetchBalance", `{"data":`+responsePathData+`}`)
+
+	nickname, err := DoGraphQLQuery[string](&api.WealthsimpleAPIBase, GraphQlQueryOpts{
+		QueryName:  "FetchBalance",
+		Variables:  map[string]any{},
+		ExpectType: reflect.TypeOf(""),
+		Extractor: ExtractorFunc(func(data any) (any, []PageInfoAt, error) {
+			edges := data.(map[string]any)["identity"].(map[string]any)["accounts"].(map[string]any)["edges"].([]any)
+			return edges[0].(map[string]any)["node"].(map[string]any)["nickname"], nil, nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nickname != "Travel" {
+		t.Errorf("got %q, want Travel", nickname)
+	}
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how transient failures (429, 5xx and network errors) are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Values below 2 disable retries
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponential backoff, it doesn't apply to delays requested through Retry-After
+	MaxBackoff time.Duration
+	// Multiplier grows the backoff after every attempt
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction, in the [0, 1] range
+	Jitter float64
+	// MaxRetryAfter is the longest delay requested through Retry-After that is waited for, the request fails
+	// right away when the server asks for more. Zero uses defaultMaxRetryAfter
+	MaxRetryAfter time.Duration
+}
+
+// defaultMaxRetryAfter caps Retry-After for policies that don't set MaxRetryAfter
+const defaultMaxRetryAfter = time.Minute
+
+// DefaultRetryPolicy is used by clients that don't configure a policy
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	MaxRetryAfter:  defaultMaxRetryAfter,
+}
+
+// NoRetryPolicy disables retries
+var NoRetryPolicy = RetryPolicy{MaxAttempts: 1}
+
+// backoff returns the delay before the given retry, attempt starts at 1
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay += delay * jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// maxRetryAfter returns MaxRetryAfter, falling back to defaultMaxRetryAfter
+func (p RetryPolicy) maxRetryAfter() time.Duration {
+	if p.MaxRetryAfter > 0 {
+		return p.MaxRetryAfter
+	}
+	return defaultMaxRetryAfter
+}
+
+// isRetryable reports whether err is a transient failure worth retrying
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServerError) {
+		return true
+	}
+
+	// Transport level failures (connection reset, timeouts, ...) are wrapped in url.Error
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// retryAfter returns the delay requested by the server through the Retry-After header, if any
+func retryAfter(err error) time.Duration {
+	var wsErr *WSAPIError
+	if !errors.As(err, &wsErr) || wsErr.Header == nil {
+		return 0
+	}
+
+	value := strings.TrimSpace(wsErr.Header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && time.Until(at) > 0 {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// isReadOnlyOperation reports whether a GraphQL document is a query, which is always safe to retry
+func isReadOnlyOperation(document string) bool {
+	document = strings.TrimSpace(document)
+	return strings.HasPrefix(document, "query") || strings.HasPrefix(document, "{")
+}
+
+// retryPolicy returns the configured policy, falling back to DefaultRetryPolicy
+func (api *WealthsimpleAPIBase) retryPolicy() RetryPolicy {
+	if api.RetryPolicy != nil {
+		return *api.RetryPolicy
+	}
+	return DefaultRetryPolicy
+}
+
+// withRetry calls fn until it succeeds, fails with a permanent error or the policy gives up
+func withRetry[T any](ctx context.Context, api *WealthsimpleAPIBase, operation string, fn func() (T, error)) (T, error) {
+	policy := api.retryPolicy()
+
+	for attempt := 1; ; attempt++ {
+		result, err := fn()
+		if err == nil || attempt >= policy.MaxAttempts || !isRetryable(ctx, err) {
+			return result, err
+		}
+
+		delay := policy.backoff(attempt)
+		if requested := retryAfter(err); requested > 0 {
+			if requested > policy.maxRetryAfter() {
+				api.logger().DebugContext(ctx, "not retrying, Retry-After exceeds the policy",
+					slog.String("operation", operation),
+					slog.Duration("retryAfter", requested),
+				)
+				return result, err
+			}
+			delay = requested
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			// Waiting would outlive the caller, surface the failure now
+			return result, err
+		}
+
+		api.logger().DebugContext(ctx, "retrying request",
+			slog.String("operation", operation),
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.String("error", err.Error()),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
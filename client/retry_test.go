@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 3}
+	want := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second}
+	for i, want := range want {
+		if got := policy.backoff(i + 1); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for range 100 {
+		if got := policy.backoff(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("backoff with jitter = %v, want within 50%% of 100ms", got)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"seconds", "3", 3 * time.Second},
+		{"date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), time.Hour},
+		{"past date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+		{"invalid", "soon", 0},
+		{"missing", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+			got := retryAfter(&WSAPIError{Err: ErrRateLimited, StatusCode: http.StatusTooManyRequests, Header: header})
+			// HTTP dates have a one second resolution
+			if got > tt.want || got < tt.want-time.Second {
+				t.Errorf("retryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// newFlakyAPI serves FetchBalance, failing the first failures requests with status and header
+func newFlakyAPI(t *testing.T, failures int32, status int, header http.Header, opts ...Option) (*fakeAPI, *WealthsimpleAPI) {
+	f := newFakeAPI(t)
+	var requests atomic.Int32
+	f.handle("FetchBalance", func(w http.ResponseWriter, req *fakeRequest) {
+		if requests.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			writeJSON(w, status, `{"message":"try again later"}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, balanceResponse)
+	})
+	api := f.newClient(opts...)
+	if err := api.RegisterQuery("FetchBalance", `query FetchBalance { balance { amount cents currency } }`); err != nil {
+		t.Fatal(err)
+	}
+	return f, api
+}
+
+func fetchBalance(api *WealthsimpleAPI) (map[string]any, error) {
+	return DoGraphQLQuery[map[string]any](&api.WealthsimpleAPIBase, GraphQlQueryOpts{
+		QueryName:        "FetchBalance",
+		Variables:        map[string]any{},
+		DataResponsePath: "balance",
+		ExpectType:       ObjectType,
+	})
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	f, api := newFlakyAPI(t, 2, http.StatusServiceUnavailable, nil)
+
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.graphQLRequests.Load(); got != 3 {
+		t.Errorf("sent %d requests, want 3", got)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	f, api := newFlakyAPI(t, 10, http.StatusBadGateway, nil)
+
+	if _, err := fetchBalance(api); !errors.Is(err, ErrServerError) {
+		t.Fatalf("err = %v, want ErrServerError", err)
+	}
+	if got := f.graphQLRequests.Load(); got != 3 {
+		t.Errorf("sent %d requests, want MaxAttempts", got)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	f, api := newFlakyAPI(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+
+	start := time.Now()
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want the requested second", elapsed)
+	}
+	if got := f.graphQLRequests.Load(); got != 2 {
+		t.Errorf("sent %d requests, want 2", got)
+	}
+}
+
+func TestRetryAfterAboveMaxRetryAfter(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxRetryAfter: time.Second}
+	f, api := newFlakyAPI(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"3600"}}, WithRetryPolicy(policy))
+
+	start := time.Now()
+	if _, err := fetchBalance(api); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("gave up after %v, want right away", elapsed)
+	}
+	if got := f.graphQLRequests.Load(); got != 1 {
+		t.Errorf("sent %d requests, want 1", got)
+	}
+}
+
+func TestRetryAfterOutlivingContext(t *testing.T) {
+	f, api := newFlakyAPI(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"30"}})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := DoGraphQLQueryWithContext[map[string]any](ctx, &api.WealthsimpleAPIBase, GraphQlQueryOpts{
+		QueryName:        "FetchBalance",
+		Variables:        map[string]any{},
+		DataResponsePath: "balance",
+		ExpectType:       ObjectType,
+	})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if got := f.graphQLRequests.Load(); got != 1 {
+		t.Errorf("sent %d requests, want 1", got)
+	}
+}
+
+func TestRetrySkipsMutations(t *testing.T) {
+	f := newFakeAPI(t)
+	f.handle("CancelOrder", func(w http.ResponseWriter, req *fakeRequest) {
+		writeJSON(w, http.StatusServiceUnavailable, `{"message":"unavailable"}`)
+	})
+	api := f.newClient()
+	if err := api.RegisterQuery("CancelOrder", `mutation CancelOrder($id: ID!) { cancelOrder(id: $id) { id } }`); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := DoGraphQLQuery[map[string]any](&api.WealthsimpleAPIBase, GraphQlQueryOpts{
+		QueryName:        "CancelOrder",
+		Variables:        map[string]any{"id": "order-1"},
+		DataResponsePath: "cancelOrder",
+		ExpectType:       ObjectType,
+	})
+	if !errors.Is(err, ErrServerError) {
+		t.Fatalf("err = %v, want ErrServerError", err)
+	}
+	if got := f.graphQLRequests.Load(); got != 1 {
+		t.Errorf("mutation sent %d times, want 1", got)
+	}
+}
@@ -1,9 +1,10 @@
 package client
 
 import (
+	"context"
 	"fmt"
 
-	"github.com/vpineda1996/wealthgo/client/graphql/generated"
+	"github.com/vpineda1996/wealthgo/client/graphql/queries"
 )
 
 // SecuritySymbol represents a security symbol with an optional exchange prefix
@@ -12,11 +13,17 @@ type SecuritySymbol string
 
 // SecurityIDToSymbol converts a security ID to a symbol
 func (api *WealthsimpleAPI) SecurityIDToSymbol(securityID string) (SecuritySymbol, error) {
+	return api.SecurityIDToSymbolWithContext(context.Background(), securityID)
+}
+
+// SecurityIDToSymbolWithContext converts a security ID to a symbol, requests are bound to ctx
+func (api *WealthsimpleAPI) SecurityIDToSymbolWithContext(ctx context.Context, securityID string) (SecuritySymbol, error) {
 	symbol := fmt.Sprintf("[%s]", securityID)
 
 	if api.SecurityMarketDataCacheGetter != nil {
-		marketData, err := api.GetSecurityMarketData(securityID, true)
-		if err != nil {
+		// The symbol of partial market data is good enough, the errors concern other fields
+		marketData, err := api.GetSecurityMarketDataWithContext(ctx, securityID, true)
+		if marketData == nil {
 			return "", err
 		}
 		if marketData != nil && marketData.Stock != nil {
@@ -31,14 +38,20 @@ func (api *WealthsimpleAPI) SecurityIDToSymbol(securityID string) (SecuritySymbo
 	return SecuritySymbol(symbol), nil
 }
 
-// SetSecurityMarketDataCache sets the cache functions for security market data
+// SetSecurityMarketDataCache sets the cache functions for security market data. It isn't synchronized with
+// requests in flight, call it before the client is shared between goroutines or use WithSecurityMarketDataCache
 func (api *WealthsimpleAPI) SetSecurityMarketDataCache(getter SecurityMarketDataCacheGetter, setter SecurityMarketDataCacheSetter) {
 	api.SecurityMarketDataCacheGetter = getter
 	api.SecurityMarketDataCacheSetter = setter
 }
 
 // GetSecurityMarketData retrieves security market data
-func (api *WealthsimpleAPI) GetSecurityMarketData(securityID string, useCache bool) (*generated.Security, error) {
+func (api *WealthsimpleAPI) GetSecurityMarketData(securityID string, useCache bool) (*queries.SecurityMarketData, error) {
+	return api.GetSecurityMarketDataWithContext(context.Background(), securityID, useCache)
+}
+
+// GetSecurityMarketDataWithContext retrieves security market data, requests are bound to ctx
+func (api *WealthsimpleAPI) GetSecurityMarketDataWithContext(ctx context.Context, securityID string, useCache bool) (*queries.SecurityMarketData, error) {
 	if useCache && api.SecurityMarketDataCacheGetter != nil {
 		cachedValue, ok := api.SecurityMarketDataCacheGetter(securityID)
 		if ok && cachedValue != nil {
@@ -46,58 +59,91 @@ func (api *WealthsimpleAPI) GetSecurityMarketData(securityID string, useCache bo
 		}
 	}
 
-	marketData, err := DoGraphQLQuery[generated.Security](
-		&api.WealthsimpleAPIBase,
-		GraphQlQueryOpts{
-			QueryName:        "FetchSecurityMarketData",
-			Variables:        map[string]any{"id": securityID},
-			DataResponsePath: "security",
-			ExpectType:       objectType,
-		},
-	)
+	var marketData *queries.SecurityMarketData
+	var err error
+	if api.securityLoader != nil {
+		// Coalesce with concurrent lookups into a single batched request
+		marketData, err = api.securityLoader.load(ctx, securityID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var data *queries.FetchSecurityMarketDataResponse
+		data, err = DoGraphQLOperation[queries.FetchSecurityMarketDataResponse](
+			ctx,
+			&api.WealthsimpleAPIBase,
+			"FetchSecurityMarketData",
+			map[string]any{"id": securityID},
+		)
+		if data == nil || data.Security == nil {
+			if err == nil {
+				err = fmt.Errorf("%w: security %s not found", ErrUnexpected, securityID)
+			}
+			return nil, err
+		}
+		marketData = data.Security
+	}
 
+	// Partial results are returned along with the GraphQL errors but aren't cached
 	if err != nil {
-		return nil, err
+		return marketData, err
 	}
 
 	if useCache && api.SecurityMarketDataCacheSetter != nil {
-		api.SecurityMarketDataCacheSetter(securityID, &marketData)
+		api.SecurityMarketDataCacheSetter(securityID, marketData)
 	}
 
-	return &marketData, nil
+	return marketData, nil
 }
 
 // GetSecurityHistoricalQuotes retrieves historical quotes for a security
-func (api *WealthsimpleAPI) GetSecurityHistoricalQuotes(securityID string, timeRange string) ([]generated.HistoricalQuote, error) {
+func (api *WealthsimpleAPI) GetSecurityHistoricalQuotes(securityID string, timeRange string) ([]queries.HistoricalQuote, error) {
+	return api.GetSecurityHistoricalQuotesWithContext(context.Background(), securityID, timeRange)
+}
+
+// GetSecurityHistoricalQuotesWithContext retrieves historical quotes for a security, requests are bound to ctx
+func (api *WealthsimpleAPI) GetSecurityHistoricalQuotesWithContext(ctx context.Context, securityID string, timeRange string) ([]queries.HistoricalQuote, error) {
 	if timeRange == "" {
 		timeRange = "1m"
 	}
 
-	result, err := DoGraphQLQuery[[]generated.HistoricalQuote](
+	data, err := DoGraphQLOperation[queries.FetchSecurityHistoricalQuotesResponse](
+		ctx,
 		&api.WealthsimpleAPIBase,
-		GraphQlQueryOpts{
-			QueryName:        "FetchSecurityHistoricalQuotes",
-			Variables:        map[string]any{"id": securityID, "timerange": timeRange},
-			DataResponsePath: "security.historicalQuotes",
-			ExpectType:       arrayType,
-		})
-	if err != nil {
+		"FetchSecurityHistoricalQuotes",
+		map[string]any{"id": securityID, "timerange": timeRange},
+	)
+	if data == nil || data.Security == nil {
+		if err == nil {
+			err = fmt.Errorf("%w: security %s not found", ErrUnexpected, securityID)
+		}
 		return nil, err
 	}
 
-	return result, nil
+	// Partial results are returned along with the GraphQL errors, if any
+	return data.Security.HistoricalQuotes, err
 }
 
 // SearchSecurity searches for a security by query
-func (api *WealthsimpleAPIBase) SearchSecurity(query string) ([]generated.Security, error) {
-	return DoGraphQLQuery[[]generated.Security](
-		api, GraphQlQueryOpts{
-			QueryName: "FetchSecuritySearchResult",
-			Variables: map[string]any{
-				"query": query,
-			},
-			DataResponsePath: "securitySearch.results",
-			ExpectType:       arrayType,
+func (api *WealthsimpleAPIBase) SearchSecurity(query string) ([]queries.SecuritySearchResult, error) {
+	return api.SearchSecurityWithContext(context.Background(), query)
+}
+
+// SearchSecurityWithContext searches for a security by query, the request is bound to ctx
+func (api *WealthsimpleAPIBase) SearchSecurityWithContext(ctx context.Context, query string) ([]queries.SecuritySearchResult, error) {
+	data, err := DoGraphQLOperation[queries.FetchSecuritySearchResultResponse](
+		ctx, api, "FetchSecuritySearchResult",
+		map[string]any{
+			"query": query,
 		},
 	)
+	if data == nil || data.SecuritySearch == nil {
+		if err == nil {
+			err = fmt.Errorf("%w: no search results for %s", ErrUnexpected, query)
+		}
+		return nil, err
+	}
+
+	// Partial results are returned along with the GraphQL errors, if any
+	return data.SecuritySearch.Results, err
 }
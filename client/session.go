@@ -10,6 +10,10 @@ type WSAPISession struct {
 	SessionID    string
 	ClientID     string
 	TokenInfo    *TokenInformation
+	// CreatedAt is when the access token was issued, in Unix seconds, and ExpiresIn its lifetime in seconds.
+	// Both are 0 when the server didn't tell
+	CreatedAt int64
+	ExpiresIn int64
 }
 
 type TokenInformation struct {
@@ -25,3 +29,41 @@ func (s *WSAPISession) ToJSON() (string, error) {
 	}
 	return string(data), nil
 }
+
+// CurrentSession returns a snapshot of the session, safe to use while other goroutines refresh it
+func (api *WealthsimpleAPIBase) CurrentSession() WSAPISession {
+	api.sessionMu.RLock()
+	defer api.sessionMu.RUnlock()
+	if api.Session == nil {
+		return WSAPISession{}
+	}
+	return *api.Session
+}
+
+// updateSession applies fn to a copy of the session and atomically swaps the copy in, so readers
+// holding the previous session never observe a partial update
+func (api *WealthsimpleAPIBase) updateSession(fn func(*WSAPISession)) WSAPISession {
+	api.sessionMu.Lock()
+	defer api.sessionMu.Unlock()
+	var next WSAPISession
+	if api.Session != nil {
+		next = *api.Session
+	}
+	fn(&next)
+	api.Session = &next
+	return next
+}
+
+// persistSessionFct returns the persist callback, if any
+func (api *WealthsimpleAPIBase) persistSessionFct() func(string) error {
+	api.sessionMu.RLock()
+	defer api.sessionMu.RUnlock()
+	return api.PersistSession
+}
+
+// setPersistSession replaces the persist callback
+func (api *WealthsimpleAPIBase) setPersistSession(persistSessionFct func(string) error) {
+	api.sessionMu.Lock()
+	defer api.sessionMu.Unlock()
+	api.PersistSession = persistSessionFct
+}
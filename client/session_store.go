@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrSessionNotFound is returned by SessionStore.Load when no session was saved
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore keeps the session of a client across runs. The client saves the session every time its
+// tokens change and deletes it once its refresh token is revoked
+type SessionStore interface {
+	// Load returns the saved session, or an error matching ErrSessionNotFound
+	Load(ctx context.Context) (*WSAPISession, error)
+	Save(ctx context.Context, session *WSAPISession) error
+	Delete(ctx context.Context) error
+}
+
+// LockingSessionStore is a SessionStore shared between processes. Token refreshes happen under its lock
+// against the latest saved session, so concurrent processes never use the same refresh token twice
+type LockingSessionStore interface {
+	SessionStore
+	// Lock waits until the store is locked or ctx is done, the returned function releases the lock
+	Lock(ctx context.Context) (func() error, error)
+}
+
+// MemorySessionStore keeps the session in memory, it is safe for concurrent use
+type MemorySessionStore struct {
+	mu      sync.Mutex
+	session *WSAPISession
+}
+
+// NewMemorySessionStore creates an empty MemorySessionStore
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{}
+}
+
+// Load returns a copy of the saved session
+func (s *MemorySessionStore) Load(ctx context.Context) (*WSAPISession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.session == nil {
+		return nil, ErrSessionNotFound
+	}
+	session := *s.session
+	return &session, nil
+}
+
+// Save keeps a copy of session
+func (s *MemorySessionStore) Save(ctx context.Context, session *WSAPISession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessionCopy := *session
+	s.session = &sessionCopy
+	return nil
+}
+
+// Delete forgets the saved session
+func (s *MemorySessionStore) Delete(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = nil
+	return nil
+}
+
+// sessionFileMode keeps session files readable by their owner only, they hold bearer tokens
+const sessionFileMode = 0o600
+
+// FileSessionStore saves the session as JSON in a file only its owner can read. Saves replace the file
+// atomically, a crash never leaves a truncated session behind. It is a LockingSessionStore, locked through
+// a ".lock" file next to the session file
+type FileSessionStore struct {
+	Path string
+}
+
+// NewFileSessionStore creates a FileSessionStore saving to path
+func NewFileSessionStore(path string) *FileSessionStore {
+	return &FileSessionStore{Path: path}
+}
+
+// Load reads the session file
+func (s *FileSessionStore) Load(ctx context.Context) (*WSAPISession, error) {
+	data, err := readSessionFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	var session WSAPISession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("%w: decoding session file %s: %v", ErrUnexpected, s.Path, err)
+	}
+	return &session, nil
+}
+
+// Save writes the session file
+func (s *FileSessionStore) Save(ctx context.Context, session *WSAPISession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.Path, data, sessionFileMode)
+}
+
+// Delete removes the session file, it is not an error if there is none
+func (s *FileSessionStore) Delete(ctx context.Context) error {
+	return removeSessionFile(s.Path)
+}
+
+// Lock takes an exclusive lock shared with the other processes using the session file
+func (s *FileSessionStore) Lock(ctx context.Context) (func() error, error) {
+	return lockFile(ctx, s.Path+".lock")
+}
+
+// readSessionFile reads path, a missing file is reported as ErrSessionNotFound
+func readSessionFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, path)
+	}
+	return data, err
+}
+
+// removeSessionFile removes path, ignoring a missing file
+func removeSessionFile(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path, then renames it over path
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Layout of an encrypted session file: version, scrypt salt, XChaCha20-Poly1305 nonce, then the sealed JSON
+// session. The version and salt are authenticated along with the session
+const (
+	encryptedSessionVersion = 1
+	encryptedSessionSaltLen = 16
+	encryptedSessionHeader  = 1 + encryptedSessionSaltLen
+)
+
+// scrypt parameters recommended for interactive use
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// EncryptedSessionStore saves the session in a file like FileSessionStore, encrypted with a key derived from
+// a passphrase. Loading with the wrong passphrase fails instead of returning a session. Like FileSessionStore, it
+// is locked through a ".lock" file
+type EncryptedSessionStore struct {
+	Path       string
+	passphrase []byte
+
+	// mu guards the key derived for salt, reused by later saves
+	mu   sync.Mutex
+	salt []byte
+	key  []byte
+}
+
+// NewEncryptedSessionStore creates an EncryptedSessionStore saving to path
+func NewEncryptedSessionStore(path, passphrase string) *EncryptedSessionStore {
+	return &EncryptedSessionStore{Path: path, passphrase: []byte(passphrase)}
+}
+
+// Load reads and decrypts the session file
+func (s *EncryptedSessionStore) Load(ctx context.Context) (*WSAPISession, error) {
+	data, err := readSessionFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < encryptedSessionHeader+chacha20poly1305.NonceSizeX || data[0] != encryptedSessionVersion {
+		return nil, fmt.Errorf("%w: %s is not an encrypted session file", ErrUnexpected, s.Path)
+	}
+
+	header := data[:encryptedSessionHeader]
+	nonce := data[encryptedSessionHeader : encryptedSessionHeader+chacha20poly1305.NonceSizeX]
+	sealed := data[encryptedSessionHeader+chacha20poly1305.NonceSizeX:]
+
+	key, err := s.deriveKey(header[1:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, sealed, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't decrypt %s, wrong passphrase or corrupted file", ErrUnexpected, s.Path)
+	}
+
+	var session WSAPISession
+	if err := json.Unmarshal(plaintext, &session); err != nil {
+		return nil, fmt.Errorf("%w: decoding session file %s: %v", ErrUnexpected, s.Path, err)
+	}
+	return &session, nil
+}
+
+// Save encrypts the session and writes it to the file
+func (s *EncryptedSessionStore) Save(ctx context.Context, session *WSAPISession) error {
+	plaintext, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	salt := s.salt
+	s.mu.Unlock()
+	if salt == nil {
+		salt = make([]byte, encryptedSessionSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+	}
+	key, err := s.deriveKey(salt)
+	if err != nil {
+		return err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+
+	data := make([]byte, 0, encryptedSessionHeader+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	data = append(data, encryptedSessionVersion)
+	data = append(data, salt...)
+	header := data[:encryptedSessionHeader]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data = append(data, nonce...)
+	data = aead.Seal(data, nonce, plaintext, header)
+	return writeFileAtomic(s.Path, data, sessionFileMode)
+}
+
+// Delete removes the session file, it is not an error if there is none
+func (s *EncryptedSessionStore) Delete(ctx context.Context) error {
+	return removeSessionFile(s.Path)
+}
+
+// Lock takes an exclusive lock shared with the other processes using the session file
+func (s *EncryptedSessionStore) Lock(ctx context.Context) (func() error, error) {
+	return lockFile(ctx, s.Path+".lock")
+}
+
+// deriveKey returns the key of salt, scrypt only runs when the salt differs from the last one used
+func (s *EncryptedSessionStore) deriveKey(salt []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key != nil && bytes.Equal(s.salt, salt) {
+		return s.key, nil
+	}
+	if len(s.passphrase) == 0 {
+		return nil, fmt.Errorf("%w: empty session passphrase", ErrUnexpected)
+	}
+
+	key, err := scrypt.Key(s.passphrase, salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	s.salt = bytes.Clone(salt)
+	s.key = key
+	return key, nil
+}
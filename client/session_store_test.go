@@ -0,0 +1,235 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSessionStores(t *testing.T) {
+	dir := t.TempDir()
+	stores := map[string]SessionStore{
+		"memory":    NewMemorySessionStore(),
+		"file":      NewFileSessionStore(filepath.Join(dir, "session.json")),
+		"encrypted": NewEncryptedSessionStore(filepath.Join(dir, "session.enc"), "passphrase"),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.Load(ctx); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("empty store: err = %v, want ErrSessionNotFound", err)
+			}
+
+			session := &WSAPISession{AccessToken: "access-1", RefreshToken: "refresh-1", CreatedAt: 1700000000, ExpiresIn: 1800, WSSDI: "device-1"}
+			for _, refreshToken := range []string{"refresh-1", "refresh-2"} {
+				session.RefreshToken = refreshToken
+				if err := store.Save(ctx, session); err != nil {
+					t.Fatal(err)
+				}
+				loaded, err := store.Load(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if *loaded != *session {
+					t.Errorf("loaded %+v, want %+v", loaded, session)
+				}
+			}
+
+			for range 2 {
+				if err := store.Delete(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := store.Load(ctx); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("deleted store: err = %v, want ErrSessionNotFound", err)
+			}
+		})
+	}
+}
+
+func TestFileSessionStoreFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	for _, store := range []SessionStore{
+		NewFileSessionStore(filepath.Join(dir, "session.json")),
+		NewEncryptedSessionStore(filepath.Join(dir, "session.enc"), "passphrase"),
+	} {
+		if err := store.Save(ctx, &WSAPISession{AccessToken: "access-1", RefreshToken: "refresh-1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("got %d files, want no temporary file left behind", len(entries))
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm() != sessionFileMode {
+			t.Errorf("%s has mode %v, want %v", entry.Name(), info.Mode().Perm(), os.FileMode(sessionFileMode))
+		}
+	}
+}
+
+func TestEncryptedSessionStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "session.enc")
+	session := &WSAPISession{AccessToken: "access-secret", RefreshToken: "refresh-secret"}
+	if err := NewEncryptedSessionStore(path, "passphrase").Save(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Error("session file holds the tokens in clear")
+	}
+
+	if loaded, err := NewEncryptedSessionStore(path, "passphrase").Load(ctx); err != nil || *loaded != *session {
+		t.Errorf("loaded %+v (%v) with the passphrase, want %+v", loaded, err, session)
+	}
+	if _, err := NewEncryptedSessionStore(path, "wrong").Load(ctx); !errors.Is(err, ErrUnexpected) {
+		t.Errorf("wrong passphrase: err = %v, want ErrUnexpected", err)
+	}
+
+	data[len(data)-1] ^= 1
+	if err := os.WriteFile(path, data, sessionFileMode); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEncryptedSessionStore(path, "passphrase").Load(ctx); !errors.Is(err, ErrUnexpected) {
+		t.Errorf("corrupted file: err = %v, want ErrUnexpected", err)
+	}
+	if _, err := NewFileSessionStore(path).Load(ctx); !errors.Is(err, ErrUnexpected) {
+		t.Errorf("encrypted file read as plain JSON: err = %v, want ErrUnexpected", err)
+	}
+}
+
+func TestFromTokenLoadsStoredSession(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeAPI(t)
+	store := NewFileSessionStore(filepath.Join(t.TempDir(), "session.json"))
+	if err := store.Save(ctx, &WSAPISession{AccessToken: "access-0", RefreshToken: "refresh-0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := FromTokenWithContext(ctx, nil, nil,
+		WithOAuthBaseURL(f.server.URL), WithGraphQLURL(f.server.URL+"/graphql"), WithSessionStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := api.CurrentSession().TokenInfo; got == nil || got.IdentityCanonicalId != "identity-1" {
+		t.Errorf("TokenInfo = %+v, want the stored token checked", got)
+	}
+}
+
+// TestRefreshRevokedDeletesSession covers a rejected refresh token, the stored session holding it is deleted
+func TestRefreshRevokedDeletesSession(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeAPI(t)
+	store := NewMemorySessionStore()
+	revoked := &WSAPISession{AccessToken: "access-0", RefreshToken: "revoked"}
+	if err := store.Save(ctx, revoked); err != nil {
+		t.Fatal(err)
+	}
+	api := f.newClient(WithSession(revoked), WithSessionStore(store))
+
+	if err := api.RefreshAccessToken(ctx); !errors.Is(err, ErrManualLogin) {
+		t.Fatalf("err = %v, want ErrManualLogin", err)
+	}
+	if _, err := store.Load(ctx); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("revoked session still stored, Load err = %v", err)
+	}
+}
+
+// TestRefreshRevokedKeepsReplacedSession covers a store updated by another client since the session was loaded,
+// its session must survive the rejection of the old refresh token
+func TestRefreshRevokedKeepsReplacedSession(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeAPI(t)
+	store := NewMemorySessionStore()
+	api := f.newClient(WithSession(&WSAPISession{AccessToken: "access-0", RefreshToken: "revoked"}), WithSessionStore(store))
+
+	replaced := &WSAPISession{AccessToken: "access-9", RefreshToken: "refresh-9"}
+	if err := store.Save(ctx, replaced); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.RefreshAccessToken(ctx); !errors.Is(err, ErrManualLogin) {
+		t.Fatalf("err = %v, want ErrManualLogin", err)
+	}
+	stored, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("replaced session was deleted: %v", err)
+	}
+	if stored.RefreshToken != "refresh-9" {
+		t.Errorf("stored refresh token = %q, want refresh-9", stored.RefreshToken)
+	}
+}
+
+// unlockableStore is a LockingSessionStore on a platform without file locks
+type unlockableStore struct {
+	*MemorySessionStore
+}
+
+func (unlockableStore) Lock(ctx context.Context) (func() error, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// TestRefreshAdoptedSessionAboutToExpire covers a session refreshed by another process long ago, it is
+// refreshed again under the lock instead of being used until it expires
+func TestRefreshAdoptedSessionAboutToExpire(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeAPI(t)
+	store := NewFileSessionStore(filepath.Join(t.TempDir(), "session.json"))
+	stored := &WSAPISession{
+		AccessToken:  "access-0",
+		RefreshToken: "refresh-0",
+		CreatedAt:    time.Now().Add(-29 * time.Minute).Unix(),
+		ExpiresIn:    1800,
+	}
+	if err := store.Save(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	api := f.newClient(WithSession(&WSAPISession{AccessToken: "old", RefreshToken: "old"}), WithSessionStore(store))
+
+	if err := api.refreshAccessToken(ctx, "old"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.tokenRequests.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want 1", got)
+	}
+	if got := api.CurrentSession().RefreshToken; got != "refresh-1" {
+		t.Errorf("client holds %s, want refresh-1", got)
+	}
+	if saved, err := store.Load(ctx); err != nil || saved.RefreshToken != "refresh-1" {
+		t.Errorf("store holds %+v (%v), want refresh-1", saved, err)
+	}
+}
+
+// TestRefreshWithoutLockSupport covers platforms without file locks, the refresh happens without the lock
+func TestRefreshWithoutLockSupport(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeAPI(t)
+	store := unlockableStore{NewMemorySessionStore()}
+	api := f.newClient(WithSessionStore(store))
+
+	if err := api.RefreshAccessToken(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := store.Load(ctx); err != nil || saved.RefreshToken != "refresh-1" {
+		t.Errorf("store holds %+v (%v), want refresh-1", saved, err)
+	}
+}
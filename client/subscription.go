@@ -0,0 +1,398 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// graphQLTransportWS is the WebSocket subprotocol spoken by subscriptions
+const graphQLTransportWS = "graphql-transport-ws"
+
+// Close codes of the graphql-transport-ws protocol
+const (
+	gqlCloseInternalError     = 4500
+	gqlCloseBadRequest        = 4400
+	gqlCloseUnauthorized      = 4401
+	gqlCloseForbidden         = 4403
+	gqlCloseInitTimeout       = 4408
+	gqlCloseSubscriberExists  = 4409
+	gqlCloseTooManyInitialise = 4429
+)
+
+// connectionAckTimeout bounds the wait for the server to acknowledge connection_init
+const connectionAckTimeout = 10 * time.Second
+
+// subscriptionID identifies the single operation running on a subscription connection
+const subscriptionID = "1"
+
+// Unwrap maps the close codes of the graphql-transport-ws protocol to the sentinel errors of the matching
+// HTTP statuses, so errors.Is(err, ErrNotAuthorized) works on a connection closed with 4401
+func (e *WebSocketCloseError) Unwrap() []error {
+	switch e.Code {
+	case gqlCloseUnauthorized:
+		return []error{ErrNotAuthorized}
+	case gqlCloseForbidden:
+		return []error{ErrForbidden}
+	case gqlCloseTooManyInitialise:
+		return []error{ErrRateLimited}
+	case gqlCloseInternalError:
+		return []error{ErrServerError}
+	}
+	return nil
+}
+
+// wsMessage is a graphql-transport-ws protocol message
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// SubscriptionEvent is a single result of a subscription. Err carries the GraphQL errors of a partial
+// result, or the failure that ended the subscription on the last event
+type SubscriptionEvent[Data any] struct {
+	Data *Data
+	Err  error
+}
+
+// Subscription is a running GraphQL subscription. Lost connections are reopened and the operation is
+// subscribed again, until the server completes it, a permanent error occurs or Close is called
+type Subscription[Data any] struct {
+	events chan SubscriptionEvent[Data]
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Events returns the channel of results, it is closed when the subscription ends
+func (s *Subscription[Data]) Events() <-chan SubscriptionEvent[Data] {
+	return s.events
+}
+
+// Close stops the subscription and waits for its connection to be closed
+func (s *Subscription[Data]) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// Subscribe starts the registered subscription queryName with variables. The first connection is opened
+// before returning so that authentication and protocol errors are reported right away, the results are then
+// delivered on Events until ctx is done or Close is called
+func Subscribe[Data any](ctx context.Context, api *WealthsimpleAPIBase, queryName string, variables map[string]any) (*Subscription[Data], error) {
+	document, ok := api.Query(queryName)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown GraphQL query %s", ErrUnexpected, queryName)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(document), "subscription") {
+		return nil, fmt.Errorf("%w: %s is not a subscription", ErrUnexpected, queryName)
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"operationName": queryName,
+		"query":         document,
+		"variables":     variables,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnexpected, err)
+	}
+
+	r := &subscriptionRunner[Data]{
+		api:       api,
+		queryName: queryName,
+		payload:   payload,
+		logger:    api.logger().With(slog.String("operationName", queryName)),
+	}
+
+	conn, err := r.connect(ctx)
+	if errors.Is(err, ErrNotAuthorized) && api.CurrentSession().RefreshToken != "" {
+		if err := api.refreshAccessToken(ctx, r.usedToken); err != nil {
+			return nil, err
+		}
+		conn, err = r.connect(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &Subscription[Data]{
+		events: make(chan SubscriptionEvent[Data]),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	r.events = sub.events
+	go func() {
+		defer close(sub.done)
+		defer close(sub.events)
+		r.run(ctx, conn)
+	}()
+	return sub, nil
+}
+
+// subscriptionRunner drives the connections of a Subscription
+type subscriptionRunner[Data any] struct {
+	api       *WealthsimpleAPIBase
+	queryName string
+	// payload is the subscribe message payload, sent again on every connection
+	payload json.RawMessage
+	events  chan SubscriptionEvent[Data]
+	logger  *slog.Logger
+	// usedToken is the access token of the last connection attempt
+	usedToken string
+}
+
+// run listens to conn, reconnecting and subscribing again whenever the connection is lost
+func (r *subscriptionRunner[Data]) run(ctx context.Context, conn *wsConn) {
+	policy := r.api.retryPolicy()
+
+	for {
+		err := r.listen(ctx, conn)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if !reconnectable(ctx, err) {
+			r.emit(ctx, SubscriptionEvent[Data]{Err: err})
+			return
+		}
+
+		// attempt counts the consecutive failures, it restarts once a connection is acknowledged. The
+		// access token is refreshed at most once per outage
+		refreshed := false
+		for attempt := 1; ; attempt++ {
+			if errors.Is(err, ErrNotAuthorized) && !refreshed && r.api.CurrentSession().RefreshToken != "" {
+				refreshed = true
+				r.logger.DebugContext(ctx, "subscription rejected the access token, refreshing")
+				if refreshErr := r.api.refreshAccessToken(ctx, r.usedToken); refreshErr != nil {
+					r.emit(ctx, SubscriptionEvent[Data]{Err: refreshErr})
+					return
+				}
+			} else if attempt >= policy.MaxAttempts {
+				r.emit(ctx, SubscriptionEvent[Data]{Err: err})
+				return
+			} else {
+				delay := policy.backoff(attempt)
+				r.logger.DebugContext(ctx, "subscription connection lost, reconnecting",
+					slog.Int("attempt", attempt),
+					slog.Duration("delay", delay),
+					slog.String("error", err.Error()),
+				)
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+
+			conn, err = r.connect(ctx)
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if !reconnectable(ctx, err) {
+				r.emit(ctx, SubscriptionEvent[Data]{Err: err})
+				return
+			}
+		}
+	}
+}
+
+// reconnectable reports whether a subscription failing with err is worth opening again
+func reconnectable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, ErrNotAuthorized) || isRetryable(ctx, err) {
+		return true
+	}
+
+	var closeErr *WebSocketCloseError
+	if errors.As(err, &closeErr) {
+		switch closeErr.Code {
+		case gqlCloseBadRequest, gqlCloseForbidden, gqlCloseSubscriberExists:
+			return false
+		}
+		return true
+	}
+	// Any other HTTP status or a protocol violation won't go away by itself
+	var wsErr *WSAPIError
+	return !errors.As(err, &wsErr) && !errors.Is(err, ErrUnexpected)
+}
+
+// connect opens a connection, waits for the server to acknowledge it and subscribes to the operation
+func (r *subscriptionRunner[Data]) connect(ctx context.Context) (*wsConn, error) {
+	if err := r.api.ensureFreshToken(ctx); err != nil {
+		return nil, err
+	}
+	session := r.api.CurrentSession()
+	r.usedToken = session.AccessToken
+
+	headers := r.api.graphQLHeaders()
+	r.api.setSessionHeaders(headers, session, true)
+	header := make(http.Header, len(headers))
+	for k, v := range headers {
+		header.Set(k, fmt.Sprintf("%v", v))
+	}
+
+	r.logger.DebugContext(ctx, "opening subscription",
+		slog.String("url", r.api.SubscriptionURL),
+		slog.Any("headers", redactedHeaders(header)),
+	)
+	conn, err := dialWebSocket(ctx, r.api.httpClient(), r.api.SubscriptionURL, header, graphQLTransportWS)
+	if err != nil {
+		var wsErr *WSAPIError
+		if errors.As(err, &wsErr) || errors.Is(err, ErrUnexpected) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrCurl, err)
+	}
+
+	// Until the subscription is sent, a slow server or a cancelled ctx closes the connection
+	timer := time.AfterFunc(connectionAckTimeout, func() {
+		_ = conn.Close(gqlCloseInitTimeout, "Connection acknowledgement timeout")
+	})
+	defer timer.Stop()
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close(wsCloseNormal, "")
+	})
+	defer stop()
+
+	params := map[string]any{}
+	if session.AccessToken != "" {
+		params["authorization"] = fmt.Sprintf("Bearer %s", session.AccessToken)
+	}
+	initPayload, _ := json.Marshal(params)
+	if err := writeWSMessage(conn, wsMessage{Type: "connection_init", Payload: initPayload}); err != nil {
+		_ = conn.Close(wsCloseNormal, "")
+		return nil, connErr(ctx, err)
+	}
+
+	for acked := false; !acked; {
+		msg, err := readWSMessage(conn)
+		if err != nil {
+			_ = conn.Close(wsCloseNormal, "")
+			return nil, connErr(ctx, err)
+		}
+		switch msg.Type {
+		case "connection_ack":
+			acked = true
+		case "ping":
+			err = writeWSMessage(conn, wsMessage{Type: "pong"})
+		case "pong":
+		default:
+			_ = conn.Close(wsCloseProtocolError, "unexpected message")
+			return nil, fmt.Errorf("%w: unexpected %s message before connection_ack", ErrUnexpected, msg.Type)
+		}
+		if err != nil {
+			_ = conn.Close(wsCloseNormal, "")
+			return nil, connErr(ctx, err)
+		}
+	}
+
+	if err := writeWSMessage(conn, wsMessage{ID: subscriptionID, Type: "subscribe", Payload: r.payload}); err != nil {
+		_ = conn.Close(wsCloseNormal, "")
+		return nil, connErr(ctx, err)
+	}
+	return conn, nil
+}
+
+// connErr prefers the cancellation of ctx to the failure it caused on the connection
+func connErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ErrCurl, ctx.Err())
+	}
+	return err
+}
+
+// listen delivers the results received on conn until the operation ends, which returns nil, or the
+// connection fails. conn is always closed on return
+func (r *subscriptionRunner[Data]) listen(ctx context.Context, conn *wsConn) error {
+	stop := context.AfterFunc(ctx, func() {
+		_ = writeWSMessage(conn, wsMessage{ID: subscriptionID, Type: "complete"})
+		_ = conn.Close(wsCloseNormal, "")
+	})
+	defer stop()
+	defer conn.Close(wsCloseNormal, "")
+
+	for {
+		msg, err := readWSMessage(conn)
+		if err != nil {
+			return err
+		}
+
+		switch msg.Type {
+		case "next":
+			var response graphQLResponse[Data]
+			if err := decodeJSON(bytes.NewReader(msg.Payload), &response); err != nil {
+				return fmt.Errorf("%w: decoding %s event: %v", ErrUnexpected, r.queryName, err)
+			}
+			event := SubscriptionEvent[Data]{Data: response.Data}
+			if len(response.Errors) > 0 {
+				event.Err = response.Errors
+			}
+			if !r.emit(ctx, event) {
+				return nil
+			}
+		case "error":
+			var errs GraphQLErrors
+			if err := json.Unmarshal(msg.Payload, &errs); err != nil || len(errs) == 0 {
+				errs = GraphQLErrors{{Message: string(msg.Payload)}}
+			}
+			r.emit(ctx, SubscriptionEvent[Data]{Err: fmt.Errorf("subscription %s failed: %w", r.queryName, errs)})
+			return nil
+		case "complete":
+			r.logger.DebugContext(ctx, "subscription completed by the server")
+			return nil
+		case "ping":
+			if err := writeWSMessage(conn, wsMessage{Type: "pong"}); err != nil {
+				return err
+			}
+		case "pong":
+		default:
+			_ = conn.Close(wsCloseProtocolError, "unexpected message")
+			return fmt.Errorf("%w: unexpected %s message", ErrUnexpected, msg.Type)
+		}
+	}
+}
+
+// emit delivers event, it returns false when ctx is done first
+func (r *subscriptionRunner[Data]) emit(ctx context.Context, event SubscriptionEvent[Data]) bool {
+	select {
+	case r.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// writeWSMessage sends a protocol message
+func writeWSMessage(conn *wsConn, msg wsMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(data)
+}
+
+// readWSMessage reads the next protocol message
+func readWSMessage(conn *wsConn) (wsMessage, error) {
+	var msg wsMessage
+	data, err := conn.ReadMessage()
+	if err != nil {
+		return msg, err
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		_ = conn.Close(wsCloseProtocolError, "invalid message")
+		return msg, fmt.Errorf("%w: invalid graphql-transport-ws message: %v", ErrUnexpected, err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,405 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testSubscription = `subscription QuoteUpdates($id: ID!) { quote(id: $id) { price } }`
+
+type quoteUpdate struct {
+	Quote struct {
+		Price string `json:"price"`
+	} `json:"quote"`
+}
+
+// wsStandIn is a graphql-transport-ws server, each accepted connection is served by the next handler
+type wsStandIn struct {
+	t        *testing.T
+	server   *httptest.Server
+	handlers []func(c *wsConn, r *http.Request)
+	conns    atomic.Int32
+	tokens   atomic.Int32
+	wg       sync.WaitGroup
+}
+
+func newWSStandIn(t *testing.T, handlers ...func(c *wsConn, r *http.Request)) *wsStandIn {
+	s := &wsStandIn{t: t, handlers: handlers}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", s.serveWebSocket)
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		n := s.tokens.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"access-%d","refresh_token":"refresh-%d"}`, n, n)
+	})
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		s.server.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+func (s *wsStandIn) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	n := int(s.conns.Add(1))
+	if n > len(s.handlers) {
+		http.Error(w, "no more connections expected", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Protocol") != graphQLTransportWS {
+		http.Error(w, "missing subprotocol", http.StatusBadRequest)
+		return
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		s.t.Error(err)
+		return
+	}
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\nSec-WebSocket-Protocol: %s\r\n\r\n",
+		wsAcceptKey(r.Header.Get("Sec-WebSocket-Key")), graphQLTransportWS)
+	if err := rw.Flush(); err != nil {
+		s.t.Error(err)
+		conn.Close()
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer conn.Close()
+		s.handlers[n-1](&wsConn{rwc: conn, br: rw.Reader}, r)
+	}()
+}
+
+// newClient creates a client talking to the stand-in with the session access-0/refresh-0
+func (s *wsStandIn) newClient(opts ...Option) *WealthsimpleAPI {
+	s.t.Helper()
+	opts = append([]Option{
+		WithOAuthBaseURL(s.server.URL),
+		WithGraphQLURL(s.server.URL + "/graphql"),
+		WithSubscriptionURL("ws" + strings.TrimPrefix(s.server.URL, "http") + "/graphql"),
+		WithSession(&WSAPISession{AccessToken: "access-0", RefreshToken: "refresh-0"}),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 2}),
+	}, opts...)
+	api, err := NewClient(opts...)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if err := api.RegisterQuery("QuoteUpdates", testSubscription); err != nil {
+		s.t.Fatal(err)
+	}
+	return api
+}
+
+// expectMessage reads the next protocol message and checks its type
+func expectMessage(t *testing.T, c *wsConn, typ string) wsMessage {
+	t.Helper()
+	msg, err := readWSMessage(c)
+	if err != nil {
+		t.Errorf("reading %s: %v", typ, err)
+		return msg
+	}
+	if msg.Type != typ {
+		t.Errorf("got %s message, want %s", msg.Type, typ)
+	}
+	return msg
+}
+
+// acceptSubscription acknowledges the connection and returns the subscribe message
+func acceptSubscription(t *testing.T, c *wsConn, wantToken string) wsMessage {
+	t.Helper()
+	init := expectMessage(t, c, "connection_init")
+	var params map[string]string
+	_ = json.Unmarshal(init.Payload, &params)
+	if got, want := params["authorization"], "Bearer "+wantToken; got != want {
+		t.Errorf("connection_init authorization = %q, want %q", got, want)
+	}
+	send(t, c, wsMessage{Type: "connection_ack"})
+	return expectMessage(t, c, "subscribe")
+}
+
+func send(t *testing.T, c *wsConn, msg wsMessage) {
+	t.Helper()
+	if err := writeWSMessage(c, msg); err != nil {
+		t.Errorf("sending %s: %v", msg.Type, err)
+	}
+}
+
+func sendNext(t *testing.T, c *wsConn, payload string) {
+	t.Helper()
+	send(t, c, wsMessage{ID: subscriptionID, Type: "next", Payload: json.RawMessage(payload)})
+}
+
+// collect reads every event until the subscription ends
+func collect[Data any](t *testing.T, sub *Subscription[Data]) []SubscriptionEvent[Data] {
+	t.Helper()
+	var events []SubscriptionEvent[Data]
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		case <-timeout:
+			t.Fatalf("subscription still running after %d events", len(events))
+		}
+	}
+}
+
+func prices(events []SubscriptionEvent[quoteUpdate]) []string {
+	var prices []string
+	for _, event := range events {
+		if event.Data != nil {
+			prices = append(prices, event.Data.Quote.Price)
+		}
+	}
+	return prices
+}
+
+func TestSubscribeDeliversEvents(t *testing.T) {
+	pong := make(chan struct{})
+	s := newWSStandIn(t, func(c *wsConn, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer access-0" {
+			t.Errorf("handshake Authorization = %q", got)
+		}
+		sub := acceptSubscription(t, c, "access-0")
+		var payload struct {
+			OperationName string         `json:"operationName"`
+			Query         string         `json:"query"`
+			Variables     map[string]any `json:"variables"`
+		}
+		if err := json.Unmarshal(sub.Payload, &payload); err != nil {
+			t.Error(err)
+		}
+		if payload.OperationName != "QuoteUpdates" || payload.Query != testSubscription || payload.Variables["id"] != "sec-1" {
+			t.Errorf("unexpected subscribe payload %s", sub.Payload)
+		}
+
+		send(t, c, wsMessage{Type: "ping"})
+		expectMessage(t, c, "pong")
+		close(pong)
+
+		sendNext(t, c, `{"data":{"quote":{"price":"1.00"}}}`)
+		sendNext(t, c, `{"data":{"quote":{"price":"2.00"}},"errors":[{"message":"stale quote","extensions":{"code":"STALE"}}]}`)
+		send(t, c, wsMessage{ID: subscriptionID, Type: "complete"})
+		_, _ = c.ReadMessage()
+	})
+
+	api := s.newClient()
+	sub, err := Subscribe[quoteUpdate](context.Background(), &api.WealthsimpleAPIBase, "QuoteUpdates", map[string]any{"id": "sec-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	events := collect(t, sub)
+	if got := prices(events); strings.Join(got, ",") != "1.00,2.00" {
+		t.Fatalf("prices = %v", got)
+	}
+	if events[0].Err != nil {
+		t.Errorf("first event error = %v", events[0].Err)
+	}
+	if !HasGraphQLErrorCode(events[1].Err, "STALE") {
+		t.Errorf("second event error = %v, want the STALE partial result error", events[1].Err)
+	}
+	select {
+	case <-pong:
+	default:
+		t.Error("ping was not answered")
+	}
+}
+
+func TestSubscribeErrorMessage(t *testing.T) {
+	s := newWSStandIn(t, func(c *wsConn, r *http.Request) {
+		acceptSubscription(t, c, "access-0")
+		send(t, c, wsMessage{ID: subscriptionID, Type: "error", Payload: json.RawMessage(`[{"message":"unknown security","extensions":{"code":"NOT_FOUND"}}]`)})
+		_, _ = c.ReadMessage()
+	})
+
+	api := s.newClient()
+	sub, err := Subscribe[quoteUpdate](context.Background(), &api.WealthsimpleAPIBase, "QuoteUpdates", map[string]any{"id": "sec-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	events := collect(t, sub)
+	if len(events) != 1 || events[0].Data != nil {
+		t.Fatalf("events = %+v, want a single error", events)
+	}
+	var errs GraphQLErrors
+	if !errors.As(events[0].Err, &errs) || !errs.HasCode("NOT_FOUND") {
+		t.Errorf("error = %v, want GraphQL errors with NOT_FOUND", events[0].Err)
+	}
+}
+
+func TestSubscribeRefreshesOnUnauthorizedClose(t *testing.T) {
+	s := newWSStandIn(t,
+		func(c *wsConn, r *http.Request) {
+			acceptSubscription(t, c, "access-0")
+			sendNext(t, c, `{"data":{"quote":{"price":"1.00"}}}`)
+			_ = c.Close(gqlCloseUnauthorized, "Unauthorized")
+		},
+		func(c *wsConn, r *http.Request) {
+			if got := r.Header.Get("Authorization"); got != "Bearer access-1" {
+				t.Errorf("handshake Authorization after refresh = %q", got)
+			}
+			acceptSubscription(t, c, "access-1")
+			sendNext(t, c, `{"data":{"quote":{"price":"2.00"}}}`)
+			send(t, c, wsMessage{ID: subscriptionID, Type: "complete"})
+			_, _ = c.ReadMessage()
+		},
+	)
+
+	api := s.newClient()
+	sub, err := Subscribe[quoteUpdate](context.Background(), &api.WealthsimpleAPIBase, "QuoteUpdates", map[string]any{"id": "sec-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	events := collect(t, sub)
+	if got := prices(events); strings.Join(got, ",") != "1.00,2.00" {
+		t.Fatalf("prices = %v", got)
+	}
+	for _, event := range events {
+		if event.Err != nil {
+			t.Errorf("unexpected error event %v", event.Err)
+		}
+	}
+	if got := s.tokens.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want 1", got)
+	}
+	if got := api.CurrentSession().RefreshToken; got != "refresh-1" {
+		t.Errorf("refresh token = %q, want refresh-1", got)
+	}
+}
+
+func TestSubscribeReconnectsAfterDrop(t *testing.T) {
+	s := newWSStandIn(t,
+		func(c *wsConn, r *http.Request) {
+			acceptSubscription(t, c, "access-0")
+			sendNext(t, c, `{"data":{"quote":{"price":"1.00"}}}`)
+			// Drop the connection without a close frame
+			_ = c.rwc.Close()
+		},
+		func(c *wsConn, r *http.Request) {
+			acceptSubscription(t, c, "access-0")
+			sendNext(t, c, `{"data":{"quote":{"price":"2.00"}}}`)
+			send(t, c, wsMessage{ID: subscriptionID, Type: "complete"})
+			_, _ = c.ReadMessage()
+		},
+	)
+
+	api := s.newClient()
+	sub, err := Subscribe[quoteUpdate](context.Background(), &api.WealthsimpleAPIBase, "QuoteUpdates", map[string]any{"id": "sec-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	events := collect(t, sub)
+	if got := prices(events); strings.Join(got, ",") != "1.00,2.00" {
+		t.Fatalf("prices = %v", got)
+	}
+	if got := s.tokens.Load(); got != 0 {
+		t.Errorf("token refreshed %d times after a dropped connection", got)
+	}
+}
+
+func TestSubscribePermanentClose(t *testing.T) {
+	s := newWSStandIn(t, func(c *wsConn, r *http.Request) {
+		acceptSubscription(t, c, "access-0")
+		_ = c.Close(gqlCloseForbidden, "Forbidden")
+	})
+
+	api := s.newClient()
+	sub, err := Subscribe[quoteUpdate](context.Background(), &api.WealthsimpleAPIBase, "QuoteUpdates", map[string]any{"id": "sec-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	events := collect(t, sub)
+	if len(events) != 1 || !errors.Is(events[0].Err, ErrForbidden) {
+		t.Fatalf("events = %+v, want a single ErrForbidden", events)
+	}
+	if got := s.conns.Load(); got != 1 {
+		t.Errorf("opened %d connections, want 1", got)
+	}
+}
+
+func TestSubscriptionCloseSendsComplete(t *testing.T) {
+	completed := make(chan struct{})
+	s := newWSStandIn(t, func(c *wsConn, r *http.Request) {
+		acceptSubscription(t, c, "access-0")
+		sendNext(t, c, `{"data":{"quote":{"price":"1.00"}}}`)
+		msg := expectMessage(t, c, "complete")
+		if msg.ID != subscriptionID {
+			t.Errorf("complete id = %q", msg.ID)
+		}
+		close(completed)
+	})
+
+	api := s.newClient()
+	sub, err := Subscribe[quoteUpdate](context.Background(), &api.WealthsimpleAPIBase, "QuoteUpdates", map[string]any{"id": "sec-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-sub.Events()
+	sub.Close()
+
+	select {
+	case <-completed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server never received complete")
+	}
+}
+
+// TestSubscribeWithClientTimeout covers an http.Client with a Timeout, which must bound the handshake only
+func TestSubscribeWithClientTimeout(t *testing.T) {
+	s := newWSStandIn(t, func(c *wsConn, r *http.Request) {
+		acceptSubscription(t, c, "access-0")
+		time.Sleep(300 * time.Millisecond)
+		sendNext(t, c, `{"data":{"quote":{"price":"1.00"}}}`)
+		send(t, c, wsMessage{ID: subscriptionID, Type: "complete"})
+		_, _ = c.ReadMessage()
+	})
+
+	api := s.newClient(WithHTTPClient(&http.Client{Timeout: 100 * time.Millisecond}))
+	sub, err := Subscribe[quoteUpdate](context.Background(), &api.WealthsimpleAPIBase, "QuoteUpdates", map[string]any{"id": "sec-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	events := collect(t, sub)
+	if got := prices(events); len(got) != 1 || got[0] != "1.00" {
+		t.Fatalf("events = %+v, want the 1.00 quote", events)
+	}
+}
+
+func TestSubscribeRejectsQueries(t *testing.T) {
+	s := newWSStandIn(t)
+	api := s.newClient()
+	_, err := Subscribe[quoteUpdate](context.Background(), &api.WealthsimpleAPIBase, "FetchSecurityMarketData", nil)
+	if !errors.Is(err, ErrUnexpected) {
+		t.Errorf("err = %v, want ErrUnexpected", err)
+	}
+	if got := s.conns.Load(); got != 0 {
+		t.Errorf("opened %d connections for a query", got)
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// tokenRefreshMargin is how long before its expiry an access token is refreshed
+const tokenRefreshMargin = time.Minute
+
+// ExpiresAt returns when the access token expires, or the zero time when it is unknown
+func (s *WSAPISession) ExpiresAt() time.Time {
+	if s.CreatedAt == 0 || s.ExpiresIn == 0 {
+		return time.Time{}
+	}
+	return time.Unix(s.CreatedAt+s.ExpiresIn, 0)
+}
+
+// needsRefresh reports whether the access token expires within tokenRefreshMargin, false when its expiry
+// is unknown
+func (s *WSAPISession) needsRefresh() bool {
+	expiresAt := s.ExpiresAt()
+	return !expiresAt.IsZero() && time.Until(expiresAt) < tokenRefreshMargin
+}
+
+// tokenLifetime reads the expires_in and created_at members of a token response. created_at defaults to now
+// when the server omits it, expiresIn is 0 when the lifetime is unknown
+func tokenLifetime(response map[string]interface{}) (createdAt, expiresIn int64) {
+	expiresIn, ok := int64Value(response["expires_in"])
+	if !ok || expiresIn <= 0 {
+		return 0, 0
+	}
+	createdAt, ok = int64Value(response["created_at"])
+	if !ok || createdAt <= 0 {
+		createdAt = time.Now().Unix()
+	}
+	return createdAt, expiresIn
+}
+
+// int64Value converts a JSON number, decoded as json.Number, float64 or a numeric string
+func int64Value(v any) (int64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+		f, err := n.Float64()
+		return int64(f), err == nil
+	case float64:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// ensureFreshToken refreshes the access token when it is about to expire. A failed refresh is only an error
+// once the token has expired, until then the current one is still usable
+func (api *WealthsimpleAPIBase) ensureFreshToken(ctx context.Context) error {
+	session := api.CurrentSession()
+	if session.RefreshToken == "" || !session.needsRefresh() {
+		return nil
+	}
+
+	api.logger().DebugContext(ctx, "access token about to expire, refreshing", slog.Time("expiresAt", session.ExpiresAt()))
+	err := api.refreshAccessToken(ctx, session.AccessToken)
+	if err != nil && time.Now().Before(session.ExpiresAt()) {
+		api.logger().DebugContext(ctx, "proactive token refresh failed", slog.String("error", err.Error()))
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// expiringSession is the session access-0/refresh-0 whose access token expires in expiresIn
+func expiringSession(expiresIn time.Duration) *WSAPISession {
+	return &WSAPISession{
+		AccessToken:  "access-0",
+		RefreshToken: "refresh-0",
+		CreatedAt:    time.Now().Add(expiresIn).Unix() - 1800,
+		ExpiresIn:    1800,
+	}
+}
+
+func TestNeedsRefresh(t *testing.T) {
+	tests := []struct {
+		name    string
+		session *WSAPISession
+		want    bool
+	}{
+		{"expiry unknown", &WSAPISession{AccessToken: "access-0"}, false},
+		{"lifetime unknown", &WSAPISession{CreatedAt: time.Now().Add(-time.Hour).Unix()}, false},
+		{"fresh", expiringSession(10 * time.Minute), false},
+		{"outside the margin", expiringSession(tokenRefreshMargin + 10*time.Second), false},
+		{"within the margin", expiringSession(tokenRefreshMargin - 10*time.Second), true},
+		{"expired", expiringSession(-time.Minute), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.session.needsRefresh(); got != tt.want {
+				t.Errorf("needsRefresh() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenLifetime(t *testing.T) {
+	if createdAt, expiresIn := tokenLifetime(map[string]any{"access_token": "access-1"}); createdAt != 0 || expiresIn != 0 {
+		t.Errorf("missing expires_in read as %d, %d", createdAt, expiresIn)
+	}
+	if createdAt, expiresIn := tokenLifetime(map[string]any{"expires_in": json.Number("1800"), "created_at": json.Number("1700000000")}); createdAt != 1700000000 || expiresIn != 1800 {
+		t.Errorf("got %d, %d, want 1700000000, 1800", createdAt, expiresIn)
+	}
+	before := time.Now().Unix()
+	if createdAt, expiresIn := tokenLifetime(map[string]any{"expires_in": float64(1800)}); createdAt < before || expiresIn != 1800 {
+		t.Errorf("got %d, %d, want created now", createdAt, expiresIn)
+	}
+}
+
+func TestProactiveRefresh(t *testing.T) {
+	f, api := newBalanceAPI(t, WithSession(expiringSession(30*time.Second)))
+	f.tokenResponse = map[string]any{"expires_in": 1800}
+
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+	// The token was refreshed before the request, which wasn't rejected and replayed
+	if got := f.tokenRequests.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want 1", got)
+	}
+	if got := f.graphQLRequests.Load(); got != 1 {
+		t.Errorf("sent %d GraphQL requests, want 1", got)
+	}
+	session := api.CurrentSession()
+	if session.AccessToken != "access-1" || time.Until(session.ExpiresAt()) < 29*time.Minute {
+		t.Errorf("session %s expires at %v, want access-1 expiring in 30 minutes", session.AccessToken, session.ExpiresAt())
+	}
+
+	// The new token is fresh
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.tokenRequests.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want 1", got)
+	}
+}
+
+func TestProactiveRefreshFreshToken(t *testing.T) {
+	f, api := newBalanceAPI(t, WithSession(expiringSession(tokenRefreshMargin+time.Minute)))
+
+	if _, err := fetchBalance(api); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.tokenRequests.Load(); got != 0 {
+		t.Errorf("token refreshed %d times, want 0", got)
+	}
+}
+
+func TestProactiveRefreshUnknownExpiry(t *testing.T) {
+	// The refresh response has no expires_in, the new token is only refreshed once rejected
+	f, api := newBalanceAPI(t, WithSession(expiringSession(time.Second)))
+
+	for range 2 {
+		if _, err := fetchBalance(api); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := f.tokenRequests.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want 1", got)
+	}
+	if session := api.CurrentSession(); !session.ExpiresAt().IsZero() {
+		t.Errorf("expiry of the new token = %v, want unknown", session.ExpiresAt())
+	}
+}
+
+func TestProactiveRefreshConcurrent(t *testing.T) {
+	f, api := newBalanceAPI(t, WithSession(expiringSession(30*time.Second)))
+	f.tokenResponse = map[string]any{"expires_in": 1800}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := fetchBalance(api); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := f.tokenRequests.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want 1", got)
+	}
+	if got := f.graphQLRequests.Load(); got != 10 {
+		t.Errorf("sent %d GraphQL requests, want 10 without replays", got)
+	}
+}
+
+func TestFetchTokenInfoLifetime(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeAPI(t)
+	f.tokenInfoResponse = map[string]any{"expires_in": 600}
+	api := f.newClient()
+
+	// The expiry of the resumed session is unknown, /token/info tells the time left
+	if err := api.CheckOAuthTokenWithContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	session := api.CurrentSession()
+	expiresAt := session.ExpiresAt()
+	if left := time.Until(expiresAt); left < 590*time.Second || left > 600*time.Second {
+		t.Errorf("token expires in %v, want 10 minutes", left)
+	}
+	if got := f.tokenRequests.Load(); got != 0 {
+		t.Errorf("token refreshed %d times, want 0", got)
+	}
+
+	// A known expiry isn't replaced
+	f.tokenInfoResponse = map[string]any{"expires_in": 60}
+	if _, err := api.fetchTokenInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if session := api.CurrentSession(); !session.ExpiresAt().Equal(expiresAt) {
+		t.Errorf("expiry changed to %v", session.ExpiresAt())
+	}
+}
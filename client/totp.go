@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// RFC 6238 parameters used by authenticator apps
+const (
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+)
+
+// totpSkewSteps are the time steps tried in order, relative to the current one, to tolerate clock skew
+var totpSkewSteps = []int64{0, -1, 1}
+
+// decodeTOTPSecret decodes a base32 authenticator seed, ignoring case, spaces and padding
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "=", "").Replace(secret))
+	if secret == "" {
+		return nil, errors.New("empty TOTP secret")
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	return key, nil
+}
+
+// totpCode computes the code of the time step counter, RFC 4226 section 5.3
+func totpCode(key []byte, counter int64) string {
+	mac := hmac.New(sha1.New, key)
+	_ = binary.Write(mac, binary.BigEndian, uint64(counter))
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0F
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7FFFFFFF
+	mod := uint32(1)
+	for range totpDigits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// totpCounter returns the time step of t
+func totpCounter(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod/time.Second)
+}
+
+// answerTOTP completes an OTP challenge with codes generated from the TOTP secret, trying the adjacent
+// time steps when the current code is rejected
+func (api *WealthsimpleAPIBase) answerTOTP(ctx context.Context, challenge *LoginChallenge) (*WSAPISession, error) {
+	counter := totpCounter(time.Now())
+
+	var err error
+	for _, skew := range totpSkewSteps {
+		_, err = challenge.Submit(ctx, totpCode(api.totpKey, counter+skew))
+		if err == nil {
+			session := api.CurrentSession()
+			return &session, nil
+		}
+		if !errors.Is(err, ErrOTPInvalid) {
+			return nil, err
+		}
+		api.logger().DebugContext(ctx, "TOTP code rejected, trying the adjacent time step", slog.Int64("skew", skew))
+	}
+	return nil, err
+}
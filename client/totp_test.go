@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 seed of the RFC 6238 test vectors, "12345678901234567890" in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	key, err := decodeTOTPSecret(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(key) != "12345678901234567890" {
+		t.Fatalf("decoded %q", key)
+	}
+
+	// RFC 6238 appendix B, SHA1, truncated to the 6 digits used by authenticator apps
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(key, totpCounter(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeTOTPSecret(t *testing.T) {
+	for _, secret := range []string{"gezd gnbv gy3t qojq gezd gnbv gy3t qojq", "GEZDGNBVGY3TQOJQ-GEZDGNBVGY3TQOJQ", rfc6238Secret + "===="} {
+		if key, err := decodeTOTPSecret(secret); err != nil || string(key) != "12345678901234567890" {
+			t.Errorf("decodeTOTPSecret(%q) = %q, %v", secret, key, err)
+		}
+	}
+	for _, secret := range []string{"", "not base32!"} {
+		if _, err := decodeTOTPSecret(secret); err == nil {
+			t.Errorf("decodeTOTPSecret(%q) accepted an invalid secret", secret)
+		}
+	}
+	if _, err := NewClient(WithTOTPSecret("1")); err == nil {
+		t.Error("NewClient accepted an invalid TOTP secret")
+	}
+}
+
+// newTOTPServer is a token endpoint asking for an OTP and accepting the code of the time step offset
+// from the current one, or of the step before it
+func newTOTPServer(t *testing.T, offset int64) (*httptest.Server, *atomic.Int32) {
+	key, _ := decodeTOTPSecret(rfc6238Secret)
+	var requests atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var form map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&form)
+
+		otp, _, _ := strings.Cut(r.Header.Get(otpHeader), ";")
+		switch {
+		case otp == "":
+			w.Header().Set(otpHeader, "required; method=app")
+			w.Header().Set(otpClaimHeader, "claim-1")
+			writeJSON(w, http.StatusUnauthorized, `{"error":"invalid_grant","error_description":"OTP required"}`)
+		case form["otp_claim"] != "claim-1":
+			writeJSON(w, http.StatusBadRequest, `{"error":"invalid_request","error_description":"missing claim"}`)
+		case otp != totpCode(key, totpCounter(time.Now())+offset) && otp != totpCode(key, totpCounter(time.Now().Add(-totpPeriod))+offset):
+			// The previous step is accepted too, the step may have changed since the client computed its code
+			writeJSON(w, http.StatusUnauthorized, `{"error":"invalid_grant","error_description":"Invalid OTP"}`)
+		default:
+			writeJSON(w, http.StatusOK, `{"access_token":"access-1","refresh_token":"refresh-1","expires_in":1800}`)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s, &requests
+}
+
+func TestLoginWithTOTPSecret(t *testing.T) {
+	// The server accepting two steps, offsets 0, -1 and 2 are only met by the client's current, previous and
+	// next step respectively
+	for _, offset := range []int64{0, -1, 2} {
+		s, _ := newTOTPServer(t, offset)
+		api, err := NewClient(WithOAuthBaseURL(s.URL), WithTOTPSecret(rfc6238Secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		session, err := api.LoginInternalWithContext(context.Background(), "user", "secret", "", nil, "")
+		if err != nil {
+			t.Fatalf("clock skewed by %d steps: %v", offset, err)
+		}
+		if session.AccessToken != "access-1" {
+			t.Errorf("access token = %q, want access-1", session.AccessToken)
+		}
+	}
+}
+
+func TestLoginWithTOTPSecretRejected(t *testing.T) {
+	s, requests := newTOTPServer(t, 5)
+	api, err := NewClient(WithOAuthBaseURL(s.URL), WithTOTPSecret(rfc6238Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := api.LoginInternalWithContext(context.Background(), "user", "secret", "", nil, ""); !errors.Is(err, ErrOTPInvalid) {
+		t.Fatalf("err = %v, want ErrOTPInvalid", err)
+	}
+	// The login, then a code per tolerated time step
+	if got := requests.Load(); got != 1+int32(len(totpSkewSteps)) {
+		t.Errorf("sent %d requests, want %d", got, 1+len(totpSkewSteps))
+	}
+}
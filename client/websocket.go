@@ -0,0 +1,310 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WebSocket opcodes, RFC 6455 section 5.2
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+// Close codes of RFC 6455 section 7.4.1
+const (
+	wsCloseNormal          = 1000
+	wsCloseNoStatus        = 1005
+	wsCloseProtocolError   = 1002
+	wsCloseMessageTooLarge = 1009
+)
+
+// wsMaxMessageSize bounds the size of a message, fragments included
+const wsMaxMessageSize = 16 << 20
+
+// wsGUID is appended to the handshake key to compute Sec-WebSocket-Accept
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocketCloseError is returned when the peer closes the WebSocket connection
+type WebSocketCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WebSocketCloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket closed with code %d: %s", e.Code, e.Reason)
+}
+
+// wsConn is a minimal client side RFC 6455 connection exchanging text messages
+type wsConn struct {
+	rwc io.ReadWriteCloser
+	br  *bufio.Reader
+	// mask is set on client connections, which must mask every frame they send
+	mask bool
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// dialWebSocket opens a WebSocket connection to rawURL through the transport of client, asking for subprotocol
+func dialWebSocket(ctx context.Context, client *http.Client, rawURL string, header http.Header, subprotocol string) (*wsConn, error) {
+	httpURL := rawURL
+	if rest, ok := strings.CutPrefix(rawURL, "wss://"); ok {
+		httpURL = "https://" + rest
+	} else if rest, ok := strings.CutPrefix(rawURL, "ws://"); ok {
+		httpURL = "http://" + rest
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if subprotocol != "" {
+		req.Header.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+
+	// A client Timeout covers reading the body, net/http then hands back a read-only body instead of the
+	// upgraded connection. The timeout bounds the handshake through ctx instead
+	if client.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.Timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+
+		clientCopy := *client
+		clientCopy.Timeout = 0
+		client = &clientCopy
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		return nil, newHTTPError(resp)
+	}
+
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: upgraded connection is not writable", ErrUnexpected)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		rwc.Close()
+		return nil, fmt.Errorf("%w: invalid Sec-WebSocket-Accept in handshake response", ErrUnexpected)
+	}
+	if subprotocol != "" && resp.Header.Get("Sec-WebSocket-Protocol") != subprotocol {
+		rwc.Close()
+		return nil, fmt.Errorf("%w: server doesn't support the %s subprotocol", ErrUnexpected, subprotocol)
+	}
+
+	return &wsConn{rwc: rwc, br: bufio.NewReader(rwc), mask: true}, nil
+}
+
+// wsAcceptKey computes the Sec-WebSocket-Accept value matching key
+func wsAcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ReadMessage returns the next data message, answering pings on the way. A close frame from the peer is
+// acknowledged and returned as a *WebSocketCloseError
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return nil, err
+			}
+		case wsOpPong:
+		case wsOpClose:
+			closeErr := &WebSocketCloseError{Code: wsCloseNoStatus}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			_ = c.Close(closeErr.Code, "")
+			return nil, closeErr
+		case wsOpText, wsOpBinary, wsOpContinuation:
+			if opcode != wsOpContinuation && message != nil {
+				_ = c.Close(wsCloseProtocolError, "unexpected data frame")
+				return nil, fmt.Errorf("%w: data frame interleaved with a fragmented message", ErrUnexpected)
+			}
+			if len(message)+len(payload) > wsMaxMessageSize {
+				_ = c.Close(wsCloseMessageTooLarge, "")
+				return nil, fmt.Errorf("%w: websocket message exceeds %d bytes", ErrUnexpected, wsMaxMessageSize)
+			}
+			message = append(message, payload...)
+			if message == nil {
+				message = []byte{}
+			}
+			if fin {
+				return message, nil
+			}
+		default:
+			_ = c.Close(wsCloseProtocolError, "unknown opcode")
+			return nil, fmt.Errorf("%w: unknown websocket opcode %d", ErrUnexpected, opcode)
+		}
+	}
+}
+
+// readFrame reads a single frame and unmasks its payload
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > wsMaxMessageSize {
+		_ = c.Close(wsCloseMessageTooLarge, "")
+		return false, 0, nil, fmt.Errorf("%w: websocket frame exceeds %d bytes", ErrUnexpected, wsMaxMessageSize)
+	}
+
+	var maskKey [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, maskKey[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= maskKey[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage sends a text message
+func (c *wsConn) WriteMessage(data []byte) error {
+	return c.writeFrame(wsOpText, data)
+}
+
+// writeFrame sends a single unfragmented frame
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|opcode)
+
+	maskBit := byte(0)
+	if c.mask {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if c.mask {
+		var maskKey [4]byte
+		if _, err := rand.Read(maskKey[:]); err != nil {
+			return err
+		}
+		frame = append(frame, maskKey[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= maskKey[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	_, err := c.rwc.Write(frame)
+	return err
+}
+
+// Close sends a close frame with code and closes the connection, it is safe to call more than once. The frame
+// is empty for wsCloseNoStatus, which RFC 6455 reserves for reporting a close frame that had no code
+func (c *wsConn) Close(code int, reason string) error {
+	var payload []byte
+	if code != wsCloseNoStatus {
+		payload = binary.BigEndian.AppendUint16(nil, uint16(code))
+		payload = append(payload, reason...)
+	}
+	err := c.writeFrame(wsOpClose, payload)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if closeErr := c.rwc.Close(); err == nil {
+		err = closeErr
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
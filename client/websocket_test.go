@@ -0,0 +1,59 @@
+package client
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+)
+
+// newWSPipe connects a client and a server wsConn in memory
+func newWSPipe(t *testing.T) (client, server *wsConn) {
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() {
+		clientConn.Close()
+		serverConn.Close()
+	})
+	client = &wsConn{rwc: clientConn, br: bufio.NewReader(clientConn), mask: true}
+	server = &wsConn{rwc: serverConn, br: bufio.NewReader(serverConn)}
+	return client, server
+}
+
+func TestWebSocketCloseReply(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   []byte
+		wantCode  int
+		wantReply []byte
+	}{
+		{"with code", binary.BigEndian.AppendUint16(nil, 4403), 4403, binary.BigEndian.AppendUint16(nil, 4403)},
+		// 1005 may only be reported locally, the close frame without code is answered with an empty one
+		{"without code", nil, wsCloseNoStatus, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newWSPipe(t)
+			reply := make(chan []byte, 1)
+			go func() {
+				if err := server.writeFrame(wsOpClose, tt.payload); err != nil {
+					t.Error(err)
+				}
+				_, opcode, payload, err := server.readFrame()
+				if err != nil || opcode != wsOpClose {
+					t.Errorf("reply opcode %d, err %v, want a close frame", opcode, err)
+				}
+				reply <- payload
+			}()
+
+			_, err := client.ReadMessage()
+			var closeErr *WebSocketCloseError
+			if !errors.As(err, &closeErr) || closeErr.Code != tt.wantCode {
+				t.Fatalf("err = %v, want a close error with code %d", err, tt.wantCode)
+			}
+			if got := <-reply; string(got) != string(tt.wantReply) {
+				t.Errorf("replied %v, want %v", got, tt.wantReply)
+			}
+		})
+	}
+}
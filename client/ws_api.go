@@ -2,85 +2,199 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/samber/lo"
-	"github.com/vpineda1996/wealthgo/client/graphql/generated"
+	"github.com/vpineda1996/wealthgo/client/graphql/queries"
 )
 
 type MarketData = map[string]any
 
-type SecurityMarketDataCacheGetter func(string) (*generated.Security, bool)
-type SecurityMarketDataCacheSetter func(string, *generated.Security)
+type SecurityMarketDataCacheGetter func(string) (*queries.SecurityMarketData, bool)
+type SecurityMarketDataCacheSetter func(string, *queries.SecurityMarketData)
 
-// WealthsimpleAPIBase is the base struct for the Wealthsimple API
+// WealthsimpleAPIBase is the base struct for the Wealthsimple API, it is safe for concurrent use
 type WealthsimpleAPIBase struct {
-	Session                       *WSAPISession
+	// Session is swapped for a new value whenever it changes and never mutated in place,
+	// use CurrentSession to read it while other goroutines use the client
+	Session *WSAPISession
+	// SecurityMarketDataCacheGetter, SecurityMarketDataCacheSetter and UserAgent are read without locking,
+	// set them before the client is shared between goroutines. The cache functions are called concurrently
 	SecurityMarketDataCacheGetter SecurityMarketDataCacheGetter
 	SecurityMarketDataCacheSetter SecurityMarketDataCacheSetter
 	UserAgent                     string
+	// HTTPClient is shared by every request so connections are pooled
+	HTTPClient *http.Client
+	// Logger receives debug traces of every request, nil disables logging
+	Logger *slog.Logger
+	// RetryPolicy applies to read-only queries and token refreshes, nil uses DefaultRetryPolicy
+	RetryPolicy *RetryPolicy
+	// OAuthRateLimiter and GraphQLRateLimiter throttle requests per endpoint, nil means unlimited
+	OAuthRateLimiter   RateLimiter
+	GraphQLRateLimiter RateLimiter
+	// PersistedQueries sends the sha256 hash of GraphQL documents instead of their text, see WithPersistedQueries
+	PersistedQueries bool
+	// PersistSession is called with the serialized session every time its tokens change
+	PersistSession func(string) error
+	// SessionStore, when set, saves the session every time its tokens change, see WithSessionStore
+	SessionStore SessionStore
 
 	// Constants
-	OAuthBaseURL   string
-	GraphQLURL     string
-	GraphQLVersion string
+	OAuthBaseURL string
+	GraphQLURL   string
+	LoginPageURL string
+	// SubscriptionURL is the WebSocket endpoint of GraphQL subscriptions
+	SubscriptionURL string
+	GraphQLVersion  string
+	// GraphQLQueries is guarded by queriesMu, use RegisterQuery and Query while the client is in use
 	GraphQLQueries map[string]string
 	ScopeReadOnly  string
 	ScopeReadWrite string
+
+	// Values of the x-ws-locale, x-ws-profile and x-platform-os headers sent with GraphQL requests
+	Locale     string
+	Profile    string
+	PlatformOS string
+
+	// sessionMu guards the Session pointer and PersistSession
+	sessionMu sync.RWMutex
+	// refreshMu makes sure a single token refresh happens at a time
+	refreshMu sync.Mutex
+	// queriesMu guards GraphQLQueries and queryHashes
+	queriesMu sync.RWMutex
+	// queryHashes holds the persisted query hash of the registered queries, keyed by document
+	queryHashes map[string]string
+	// totpSecret is the base32 seed set by WithTOTPSecret, decoded into totpKey by NewClient
+	totpSecret string
+	totpKey    []byte
+	// persistedQueriesUnsupported is set once the server rejected persisted queries
+	persistedQueriesUnsupported atomic.Bool
 }
 
 // WealthsimpleAPI extends WealthsimpleAPIBase with additional functionality
 type WealthsimpleAPI struct {
 	WealthsimpleAPIBase
-	AccountCache map[string][]generated.Account
+	// AccountCache is guarded by accountCacheMu, don't access it while the client is in use
+	AccountCache   map[string][]queries.AccountWithFinancials
+	accountCacheMu sync.RWMutex
+
+	// securityLoader batches concurrent market data lookups, nil when batching is disabled
+	securityLoader *securityLoader
 }
 
+// Default endpoints and headers used when no option overrides them
+const (
+	DefaultOAuthBaseURL    = "https://api.production.wealthsimple.com/v1/oauth/v2"
+	DefaultGraphQLURL      = "https://my.wealthsimple.com/graphql"
+	DefaultLoginPageURL    = "https://my.wealthsimple.com/app/login"
+	DefaultSubscriptionURL = "wss://my.wealthsimple.com/graphql"
+	DefaultGraphQLVersion  = "12"
+	DefaultLocale          = "en-CA"
+	DefaultProfile         = "trade"
+	DefaultPlatformOS      = "web"
+	DefaultScopeReadOnly   = "invest.read trade.read tax.read"
+	DefaultScopeReadWrite  = "invest.read trade.read tax.read invest.write trade.write tax.write"
+)
+
 //go:embed graphql/queries/*.graphql
 var graphQlQueries embed.FS
 
-// newWealthsimpleAPI creates a new WealthsimpleAPI instance
-func newWealthsimpleAPI(sess *WSAPISession) *WealthsimpleAPI {
+// defaultHTTPClient is used when no client is supplied, it relies on the
+// pooled http.DefaultTransport
+var defaultHTTPClient = &http.Client{Transport: http.DefaultTransport}
+
+// embeddedQueries holds the GraphQL documents shipped with the library, keyed by operation name
+var embeddedQueries = loadEmbeddedQueries()
+
+// loadEmbeddedQueries reads the embedded GraphQL query files
+func loadEmbeddedQueries() map[string]string {
+	queries, err := readQueries(graphQlQueries, "graphql/queries")
+	if err != nil {
+		panic(fmt.Errorf("failed to read embedded GraphQL files: %v", err))
+	}
+	return queries
+}
+
+// NewClient creates a WealthsimpleAPI configured by opts. No request is sent, use WithSession to
+// resume an existing session or Login to create one
+func NewClient(opts ...Option) (*WealthsimpleAPI, error) {
 	api := &WealthsimpleAPI{
 		WealthsimpleAPIBase: WealthsimpleAPIBase{
-			OAuthBaseURL:   "https://api.production.wealthsimple.com/v1/oauth/v2",
-			GraphQLURL:     "https://my.wealthsimple.com/graphql",
-			GraphQLVersion: "12",
-			GraphQLQueries: make(map[string]string),
-			ScopeReadOnly:  "invest.read trade.read tax.read",
-			ScopeReadWrite: "invest.read trade.read tax.read invest.write trade.write tax.write",
-			Session:        &WSAPISession{},
+			OAuthBaseURL:    DefaultOAuthBaseURL,
+			GraphQLURL:      DefaultGraphQLURL,
+			LoginPageURL:    DefaultLoginPageURL,
+			SubscriptionURL: DefaultSubscriptionURL,
+			GraphQLVersion:  DefaultGraphQLVersion,
+			GraphQLQueries:  make(map[string]string, len(embeddedQueries)),
+			ScopeReadOnly:   DefaultScopeReadOnly,
+			ScopeReadWrite:  DefaultScopeReadWrite,
+			Locale:          DefaultLocale,
+			Profile:         DefaultProfile,
+			PlatformOS:      DefaultPlatformOS,
+			Session:         &WSAPISession{},
+			HTTPClient:      defaultHTTPClient,
 		},
-		AccountCache: make(map[string][]generated.Account),
+		AccountCache: make(map[string][]queries.AccountWithFinancials),
 	}
 
-	// Read GraphQL query files into the api.GraphQLQueries map
-	files, err := graphQlQueries.ReadDir("graphql/queries")
-	if err != nil {
-		panic(fmt.Errorf("failed to read embedded GraphQL files: %v", err))
+	for name, query := range embeddedQueries {
+		api.GraphQLQueries[name] = query
 	}
 
-	for _, file := range files {
-		if !file.IsDir() {
-			content, err := graphQlQueries.ReadFile("graphql/queries/" + file.Name())
-			if err != nil {
-				panic(fmt.Errorf("failed to read file %s: %v", file.Name(), err))
-			}
-			queryName := strings.TrimSuffix(file.Name(), ".graphql")
-			api.GraphQLQueries[queryName] = string(content)
+	for _, opt := range opts {
+		opt(api)
+	}
+
+	for name, endpoint := range map[string]string{
+		"OAuth base URL":   api.OAuthBaseURL,
+		"GraphQL URL":      api.GraphQLURL,
+		"login page URL":   api.LoginPageURL,
+		"subscription URL": api.SubscriptionURL,
+	} {
+		if u, err := url.Parse(endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("%w: invalid %s %q", ErrUnexpected, name, endpoint)
 		}
 	}
 
-	api.StartSession(sess)
-	return api
+	if api.totpSecret != "" {
+		key, err := decodeTOTPSecret(api.totpSecret)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnexpected, err)
+		}
+		api.totpKey = key
+		api.totpSecret = ""
+	}
+
+	return api, nil
+}
+
+// newWealthsimpleAPI creates a new WealthsimpleAPI instance and starts its session
+func newWealthsimpleAPI(ctx context.Context, sess *WSAPISession, opts ...Option) (*WealthsimpleAPI, error) {
+	api, err := NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := api.StartSessionWithContext(ctx, sess); err != nil {
+		return nil, err
+	}
+	return api, nil
 }
 
 // UUIDv4 generates a new UUID v4
@@ -88,37 +202,72 @@ func UUIDv4() string {
 	return uuid.New().String()
 }
 
-// SetUserAgent sets the user agent for API requests
+// SetUserAgent sets the user agent for API requests. It isn't synchronized with requests in flight, call it
+// before the client is shared between goroutines or use WithUserAgent
 func (api *WealthsimpleAPI) SetUserAgent(userAgent string) {
 	api.UserAgent = userAgent
 }
 
 // SendHTTPRequest sends an HTTP request to the specified URL
 func (api *WealthsimpleAPIBase) SendHTTPRequest(url string, method string, data map[string]interface{}, headers map[string]interface{}, returnHeaders bool) (interface{}, error) {
-	if headers == nil {
-		headers = make(map[string]interface{})
-	}
+	return api.SendHTTPRequestWithContext(context.Background(), url, method, data, headers, returnHeaders)
+}
 
-	if method == "POST" {
-		headers["Content-Type"] = "application/json"
+// SendHTTPRequestWithContext sends an HTTP request to the specified URL, the request
+// is bound to ctx so it can be cancelled or given a deadline. JSON numbers in the decoded
+// response are json.Number values so they keep their exact precision
+func (api *WealthsimpleAPIBase) SendHTTPRequestWithContext(ctx context.Context, url string, method string, data map[string]interface{}, headers map[string]interface{}, returnHeaders bool) (interface{}, error) {
+	resp, err := api.doHTTPRequest(ctx, url, method, data, headers)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
+
+	if returnHeaders {
+		// Combine headers and body as a single string
+		var headerStr strings.Builder
+		for k, v := range resp.Header {
+			headerStr.WriteString(fmt.Sprintf("%s: %s\r\n", k, strings.Join(v, ", ")))
+		}
+		headerStr.WriteString("\r\n")
+
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCurl, err)
+		}
 
-	if api.Session.SessionID != "" {
-		headers["x-ws-session-id"] = api.Session.SessionID
+		return headerStr.String() + string(bodyBytes), nil
 	}
 
-	if api.Session.AccessToken != "" && (data == nil || data["grant_type"] != "refresh_token") {
-		headers["Authorization"] = fmt.Sprintf("Bearer %s", api.Session.AccessToken)
+	var result interface{}
+	err = decodeJSON(resp.Body, &result)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCurl, err)
 	}
+	return result, nil
+}
+
+// decodeJSON decodes a JSON document keeping numbers exact: values decoded into interface{} become
+// json.Number instead of float64, so integers beyond 2^53 survive being passed around and re-encoded
+func decodeJSON(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
 
-	if api.Session.WSSDI != "" {
-		headers["x-ws-device-id"] = api.Session.WSSDI
+// doHTTPRequest sends an HTTP request with the session headers and returns the response of a successful
+// call, the caller must close its body. Non successful statuses are returned as a WSAPIError
+func (api *WealthsimpleAPIBase) doHTTPRequest(ctx context.Context, url string, method string, data map[string]interface{}, headers map[string]interface{}) (*http.Response, error) {
+	if headers == nil {
+		headers = make(map[string]interface{})
 	}
 
-	if api.UserAgent != "" {
-		headers["User-Agent"] = api.UserAgent
+	if method == "POST" {
+		headers["Content-Type"] = "application/json"
 	}
 
+	api.setSessionHeaders(headers, api.CurrentSession(), data == nil || data["grant_type"] != "refresh_token")
+
 	var reqBody io.Reader
 	if data != nil {
 		jsonData, err := json.Marshal(data)
@@ -128,7 +277,7 @@ func (api *WealthsimpleAPIBase) SendHTTPRequest(url string, method string, data
 		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest(method, url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrCurl, err)
 	}
@@ -138,65 +287,217 @@ func (api *WealthsimpleAPIBase) SendHTTPRequest(url string, method string, data
 		req.Header.Set(k, fmt.Sprintf("%v", v))
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrCurl, err)
+	logger := api.logger().With(
+		slog.String("method", method),
+		slog.String("url", url),
+	)
+	if operationName, ok := data["operationName"].(string); ok {
+		logger = logger.With(slog.String("operationName", operationName))
 	}
-	defer resp.Body.Close()
+	logger.DebugContext(ctx, "sending request",
+		slog.Any("headers", redactedHeaders(req.Header)),
+		slog.Any("payload", redactedPayload(data)),
+	)
 
-	if returnHeaders {
-		// Combine headers and body as a single string
-		var headerStr strings.Builder
-		for k, v := range resp.Header {
-			headerStr.WriteString(fmt.Sprintf("%s: %s\r\n", k, strings.Join(v, ", ")))
+	if limiter := api.rateLimiterFor(url); limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCurl, err)
 		}
-		headerStr.WriteString("\r\n")
+	}
 
-		bodyBytes, err := io.ReadAll(resp.Body)
+	start := time.Now()
+	resp, err := api.httpClient().Do(req)
+	if err != nil {
+		logger.DebugContext(ctx, "request failed",
+			slog.Duration("latency", time.Since(start)),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("%w: %w", ErrCurl, err)
+	}
+
+	logger.DebugContext(ctx, "received response",
+		slog.Int("status", resp.StatusCode),
+		slog.Duration("latency", time.Since(start)),
+	)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, newHTTPError(resp)
+	}
+	return resp, nil
+}
+
+// graphQLResponse is the envelope of a GraphQL response, Data is decoded straight into its final type
+type graphQLResponse[Data any] struct {
+	Data       *Data          `json:"data"`
+	Errors     GraphQLErrors  `json:"errors"`
+	Extensions map[string]any `json:"extensions"`
+	// Message is set by the gateway when it rejects a request before it reaches GraphQL
+	Message string `json:"message"`
+}
+
+// postGraphQL sends a GraphQL request and decodes the response in a single pass. The result may carry
+// partial data along with errors, a response without data is returned as an error. An unauthorized response
+// triggers a single token refresh, shared with any concurrent caller, after which the request is replayed once
+func postGraphQL[Data any](ctx context.Context, api *WealthsimpleAPIBase, query map[string]any, headers map[string]any, retryable bool) (*graphQLResponse[Data], error) {
+	queryName, _ := query["operationName"].(string)
+
+	post := func(body map[string]any) (*graphQLResponse[Data], error) {
+		resp, err := api.doHTTPRequest(ctx, api.GraphQLURL, http.MethodPost, body, headers)
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrCurl, err)
+			var wsErr *WSAPIError
+			if errors.As(err, &wsErr) && wsErr.Response != nil {
+				wsErr.GraphQLErrors, _ = parseGraphQLErrors(wsErr.Response["errors"])
+			}
+			return nil, err
 		}
+		defer resp.Body.Close()
 
-		return headerStr.String() + string(bodyBytes), nil
+		var response graphQLResponse[Data]
+		if err := decodeJSON(resp.Body, &response); err != nil {
+			return nil, fmt.Errorf("%w: decoding response of %s: %v", ErrCurl, queryName, err)
+		}
+
+		if response.Data == nil {
+			wsErr := &WSAPIError{
+				Err:           ErrWSApi,
+				StatusCode:    resp.StatusCode,
+				Header:        resp.Header,
+				RequestID:     requestID(resp.Header),
+				GraphQLErrors: response.Errors,
+			}
+			if response.Message != "" {
+				wsErr.Response = map[string]interface{}{"message": response.Message}
+			}
+			return nil, fmt.Errorf("no data present in request %s: %w", queryName, wsErr)
+		}
+		return &response, nil
+	}
+	send := func() (*graphQLResponse[Data], error) {
+		if !api.usePersistedQueries() {
+			return post(query)
+		}
+
+		hashed, full := api.persistedQueryRequests(query)
+		response, err := post(hashed)
+		switch {
+		case isPersistedQueryError(err, persistedQueryNotFound, persistedQueryNotFoundCode):
+			// The server doesn't know the hash yet, it stores the document sent along with it
+			api.logger().DebugContext(ctx, "persisted query not found, sending the document", slog.String("operationName", queryName))
+			return post(full)
+		case isPersistedQueryError(err, persistedQueryNotSupported, persistedQueryNotSupportedCode):
+			api.logger().DebugContext(ctx, "persisted queries not supported, disabling them")
+			api.persistedQueriesUnsupported.Store(true)
+			return post(query)
+		}
+		return response, err
+	}
+	attempt := func() (*graphQLResponse[Data], error) {
+		if retryable {
+			return withRetry(ctx, api, queryName, send)
+		}
+		return send()
 	}
 
-	fmt.Println("Response Status:", resp.Status)
+	if err := api.ensureFreshToken(ctx); err != nil {
+		return nil, err
+	}
+	usedToken := api.CurrentSession().AccessToken
+	response, err := attempt()
+	if err == nil || !errors.Is(err, ErrNotAuthorized) || api.CurrentSession().RefreshToken == "" {
+		return response, err
+	}
 
-	var result interface{}
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrCurl, err)
+	api.logger().DebugContext(ctx, "access token rejected, refreshing", slog.String("operationName", queryName))
+	if err := api.refreshAccessToken(ctx, usedToken); err != nil {
+		return nil, err
 	}
-	return result, nil
+	return attempt()
+}
+
+// setSessionHeaders adds the headers identifying session to headers, its access token included when withAuth is set
+func (api *WealthsimpleAPIBase) setSessionHeaders(headers map[string]interface{}, session WSAPISession, withAuth bool) {
+	if session.SessionID != "" {
+		headers["x-ws-session-id"] = session.SessionID
+	}
+
+	if session.AccessToken != "" && withAuth {
+		headers["Authorization"] = fmt.Sprintf("Bearer %s", session.AccessToken)
+	}
+
+	if session.WSSDI != "" {
+		headers["x-ws-device-id"] = session.WSSDI
+	}
+
+	if api.UserAgent != "" {
+		headers["User-Agent"] = api.UserAgent
+	}
+}
+
+// graphQLHeaders returns the headers sent with every GraphQL request
+func (api *WealthsimpleAPIBase) graphQLHeaders() map[string]any {
+	return map[string]any{
+		"x-ws-profile":     api.Profile,
+		"x-ws-api-version": api.GraphQLVersion,
+		"x-ws-locale":      api.Locale,
+		"x-platform-os":    api.PlatformOS,
+	}
+}
+
+// httpClient returns the configured HTTP client, falling back to the shared default
+func (api *WealthsimpleAPIBase) httpClient() *http.Client {
+	if api.HTTPClient != nil {
+		return api.HTTPClient
+	}
+	return defaultHTTPClient
 }
 
 // SendGet sends a GET request
 func (api *WealthsimpleAPIBase) SendGet(url string, headers map[string]interface{}, returnHeaders bool) (interface{}, error) {
-	return api.SendHTTPRequest(url, http.MethodGet, nil, headers, returnHeaders)
+	return api.SendGetWithContext(context.Background(), url, headers, returnHeaders)
+}
+
+// SendGetWithContext sends a GET request bound to ctx
+func (api *WealthsimpleAPIBase) SendGetWithContext(ctx context.Context, url string, headers map[string]interface{}, returnHeaders bool) (interface{}, error) {
+	return api.SendHTTPRequestWithContext(ctx, url, http.MethodGet, nil, headers, returnHeaders)
 }
 
 // SendPost sends a POST request
 func (api *WealthsimpleAPIBase) SendPost(url string, data map[string]interface{}, headers map[string]interface{}, returnHeaders bool) (interface{}, error) {
-	return api.SendHTTPRequest(url, http.MethodPost, data, headers, returnHeaders)
+	return api.SendPostWithContext(context.Background(), url, data, headers, returnHeaders)
+}
+
+// SendPostWithContext sends a POST request bound to ctx
+func (api *WealthsimpleAPIBase) SendPostWithContext(ctx context.Context, url string, data map[string]interface{}, headers map[string]interface{}, returnHeaders bool) (interface{}, error) {
+	return api.SendHTTPRequestWithContext(ctx, url, http.MethodPost, data, headers, returnHeaders)
 }
 
 // StartSession initializes a session
 func (api *WealthsimpleAPIBase) StartSession(sess *WSAPISession) error {
+	return api.StartSessionWithContext(context.Background(), sess)
+}
+
+// StartSessionWithContext initializes a session, fetching the device id and client id if needed
+func (api *WealthsimpleAPIBase) StartSessionWithContext(ctx context.Context, sess *WSAPISession) error {
 	if sess != nil {
-		api.Session.AccessToken = sess.AccessToken
-		api.Session.WSSDI = sess.WSSDI
-		api.Session.SessionID = sess.SessionID
-		api.Session.ClientID = sess.ClientID
-		api.Session.RefreshToken = sess.RefreshToken
+		api.updateSession(func(s *WSAPISession) {
+			s.AccessToken = sess.AccessToken
+			s.WSSDI = sess.WSSDI
+			s.SessionID = sess.SessionID
+			s.ClientID = sess.ClientID
+			s.RefreshToken = sess.RefreshToken
+			s.CreatedAt = sess.CreatedAt
+			s.ExpiresIn = sess.ExpiresIn
+		})
 		return nil
 	}
 
 	var appJSURL string
+	session := api.CurrentSession()
 
-	if api.Session.WSSDI == "" || api.Session.ClientID == "" {
+	if session.WSSDI == "" || session.ClientID == "" {
 		// Fetch login page
-		response, err := api.SendGet("https://my.wealthsimple.com/app/login", nil, true)
+		response, err := api.SendGetWithContext(ctx, api.LoginPageURL, nil, true)
 		if err != nil {
 			return err
 		}
@@ -207,11 +508,11 @@ func (api *WealthsimpleAPIBase) StartSession(sess *WSAPISession) error {
 		}
 
 		// Look for wssdi in set-cookie headers
-		if api.Session.WSSDI == "" {
+		if session.WSSDI == "" {
 			re := regexp.MustCompile(`(?i)wssdi=([a-f0-9]+);`)
 			matches := re.FindStringSubmatch(responseStr)
 			if len(matches) > 1 {
-				api.Session.WSSDI = matches[1]
+				session = api.updateSession(func(s *WSAPISession) { s.WSSDI = matches[1] })
 			}
 		}
 
@@ -224,18 +525,18 @@ func (api *WealthsimpleAPIBase) StartSession(sess *WSAPISession) error {
 			}
 		}
 
-		if api.Session.WSSDI == "" {
+		if session.WSSDI == "" {
 			return fmt.Errorf("%w: couldn't find wssdi in login page response headers", ErrUnexpected)
 		}
 	}
 
-	if api.Session.ClientID == "" {
+	if session.ClientID == "" {
 		if appJSURL == "" {
 			return fmt.Errorf("%w: couldn't find app JS URL in login page response body", ErrUnexpected)
 		}
 
 		// Fetch the app JS file
-		response, err := api.SendGet(appJSURL, nil, true)
+		response, err := api.SendGetWithContext(ctx, appJSURL, nil, true)
 		if err != nil {
 			return err
 		}
@@ -249,145 +550,156 @@ func (api *WealthsimpleAPIBase) StartSession(sess *WSAPISession) error {
 		re := regexp.MustCompile(`(?i)production:.*clientId:"([a-f0-9]+)"`)
 		matches := re.FindStringSubmatch(responseStr)
 		if len(matches) > 1 {
-			api.Session.ClientID = matches[1]
+			session = api.updateSession(func(s *WSAPISession) { s.ClientID = matches[1] })
 		}
 
-		if api.Session.ClientID == "" {
+		if session.ClientID == "" {
 			return fmt.Errorf("%w: couldn't find clientId in app JS", ErrUnexpected)
 		}
 	}
 
-	if api.Session.SessionID == "" {
-		api.Session.SessionID = UUIDv4()
+	if session.SessionID == "" {
+		api.updateSession(func(s *WSAPISession) { s.SessionID = UUIDv4() })
 	}
 
 	return nil
 }
 
 var (
-	objectType = reflect.TypeOf(map[string]interface{}{})
-	arrayType  = reflect.TypeOf([]map[string]interface{}{})
+	// ObjectType and ArrayType are the GraphQlQueryOpts.ExpectType values for object and list results
+	ObjectType = reflect.TypeOf(map[string]interface{}{})
+	ArrayType  = reflect.TypeOf([]map[string]interface{}{})
 
 	validate = validator.New(validator.WithRequiredStructEnabled())
 )
 
 type GraphQlQueryOpts struct {
-	QueryName        string         `validate:"required,min=1"`
-	Variables        map[string]any `validate:"required"`
-	DataResponsePath string         `validate:"required,min=1"`
-	ExpectType       reflect.Type   `validate:"required"`
+	QueryName string         `validate:"required,min=1"`
+	Variables map[string]any `validate:"required"`
+	// DataResponsePath selects the value to decode, see ResponsePath for its syntax
+	DataResponsePath string `validate:"required_without=Extractor"`
+	// Extractor replaces DataResponsePath when set
+	Extractor  ResponseExtractor
+	ExpectType reflect.Type `validate:"required"`
+	// Idempotent allows a mutation to be retried on transient failures, queries are always retried
+	Idempotent bool
+}
+
+// QueryResult is the value extracted from a GraphQL response, along with the pagination state of the
+// connections traversed and the extensions of the response
+type QueryResult[T any] struct {
+	Data T
+	// PageInfo is the pageInfo of the last connection traversed, nil when none was
+	PageInfo *PageInfo
+	// PageInfos holds the pageInfo of every connection traversed, keyed by the path of the connection
+	PageInfos  map[string]*PageInfo
+	Extensions map[string]any
 }
 
+// DoGraphQLQuery runs one of the registered GraphQL queries and decodes the value found at DataResponsePath.
+// When the response carries both data and errors, the partial result is returned along with a GraphQLErrors error
 func DoGraphQLQuery[ResponseType any](api *WealthsimpleAPIBase, opts GraphQlQueryOpts) (ResponseType, error) {
+	return DoGraphQLQueryWithContext[ResponseType](context.Background(), api, opts)
+}
+
+// DoGraphQLQueryWithContext is like DoGraphQLQuery but the request is bound to ctx
+func DoGraphQLQueryWithContext[ResponseType any](ctx context.Context, api *WealthsimpleAPIBase, opts GraphQlQueryOpts) (ResponseType, error) {
+	result, err := DoGraphQLQueryResult[ResponseType](ctx, api, opts)
+	if result == nil {
+		return lo.Empty[ResponseType](), err
+	}
+	return result.Data, err
+}
+
+// DoGraphQLQueryResult is like DoGraphQLQueryWithContext but returns the whole QueryResult envelope.
+// The result is nil when nothing could be extracted
+func DoGraphQLQueryResult[ResponseType any](ctx context.Context, api *WealthsimpleAPIBase, opts GraphQlQueryOpts) (*QueryResult[ResponseType], error) {
 	// Validate the GraphQlQueryOpts struct
 	if err := validate.Struct(opts); err != nil {
-		return lo.Empty[ResponseType](), fmt.Errorf("validation error: %w", err)
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	extractor := opts.Extractor
+	if extractor == nil {
+		path, err := ParseResponsePath(opts.DataResponsePath)
+		if err != nil {
+			return nil, err
+		}
+		extractor = path
 	}
 
 	queryName := opts.QueryName
-	variables := opts.Variables
-	dataResponsePath := opts.DataResponsePath
-	expectType := opts.ExpectType
+	document, ok := api.Query(queryName)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown GraphQL operation %s", ErrUnexpected, queryName)
+	}
 
 	query := map[string]any{
 		"operationName": queryName,
-		"query":         api.GraphQLQueries[queryName],
-		"variables":     variables,
+		"query":         document,
+		"variables":     opts.Variables,
 	}
 
-	headers := map[string]any{
-		"x-ws-profile":     "trade",
-		"x-ws-api-version": api.GraphQLVersion,
-		"x-ws-locale":      "en-CA",
-		"x-platform-os":    "web",
-	}
-
-	response, err := api.SendPost(
-		api.GraphQLURL,
-		query,
-		headers,
-		false,
-	)
-
-	empty := lo.Empty[ResponseType]()
+	headers := api.graphQLHeaders()
 
+	retryable := opts.Idempotent || isReadOnlyOperation(document)
+	response, err := postGraphQL[any](ctx, api, query, headers, retryable)
 	if err != nil {
-		return empty, err
-	}
-
-	responseMap, ok := response.(map[string]interface{})
-	if !ok {
-		return empty, fmt.Errorf("%w: unexpected response type", ErrUnexpected)
+		return nil, err
 	}
 
-	data, ok := responseMap["data"]
-	if !ok {
-		return empty, fmt.Errorf("no data present in request %s: %w", queryName,
-			&WSAPIError{Err: ErrWSApi, Response: responseMap})
+	result, err := extractResponse[ResponseType](*response.Data, extractor, opts.ExpectType)
+	if err != nil {
+		if len(response.Errors) > 0 {
+			// The value is missing because of the reported errors, they explain the failure better
+			return nil, fmt.Errorf("request %s failed: %w", queryName, response.Errors)
+		}
+		return nil, err
 	}
+	result.Extensions = response.Extensions
 
-	if data == nil {
-		return empty, fmt.Errorf("data is nil on request %s: %w", queryName,
-			&WSAPIError{Err: ErrWSApi, Response: responseMap})
+	if len(response.Errors) > 0 {
+		return result, response.Errors
 	}
+	return result, nil
+}
 
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		return empty, fmt.Errorf("%w: unexpected data type", ErrUnexpected)
+// extractResponse runs extractor on data and decodes the value it picked into ResponseType
+func extractResponse[ResponseType any](data any, extractor ResponseExtractor, expectType reflect.Type) (*QueryResult[ResponseType], error) {
+	if _, ok := data.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("%w: unexpected data type", ErrUnexpected)
 	}
 
-	// Navigate through the response path
-	pathParts := strings.Split(dataResponsePath, ".")
-	var result any = dataMap
-	for _, part := range pathParts {
-		resultMap, ok := result.(map[string]interface{})
-		if !ok {
-			return empty, fmt.Errorf("%w: unexpected data structure", ErrUnexpected)
-		}
-		result = resultMap[part]
-		if result == nil {
-			return empty, fmt.Errorf("%w: path %s not found in response", ErrUnexpected, part)
-		}
-
-		if part == "edges" {
-			edges, ok := result.([]any)
-			if !ok {
-				return empty, fmt.Errorf("%w: unexpected data structure", ErrUnexpected)
-			}
-			nds := make([]any, len(edges))
-			for i, edge := range edges {
-				edgeMap, ok := edge.(map[string]any)
-				if !ok {
-					return empty, fmt.Errorf("%w: unexpected data structure", ErrUnexpected)
-				}
-				nd, ok := edgeMap["node"]
-				if !ok {
-					return empty, fmt.Errorf("%w: unexpected data structure", ErrUnexpected)
-				}
-				nds[i] = nd
-			}
-			result = nds
-		}
+	value, pageInfos, err := extractor.Extract(data)
+	if err != nil {
+		return nil, err
 	}
 
 	if expectType != nil {
-		resultValue := reflect.ValueOf(result)
+		resultValue := reflect.ValueOf(value)
 		if resultValue.Kind() != expectType.Kind() {
-			return empty, fmt.Errorf("%w: expected type %s, got %s", ErrUnexpected, expectType, resultValue.Kind())
+			return nil, fmt.Errorf("%w: expected type %s, got %s", ErrUnexpected, expectType, resultValue.Kind())
 		}
 	}
 
-	// HACK!
-	b, err := json.Marshal(result)
-	if err != nil {
-		return empty, fmt.Errorf("%v, %w", ErrUnexpected, err)
+	result := &QueryResult[ResponseType]{PageInfos: make(map[string]*PageInfo, len(pageInfos))}
+	if typed, ok := value.(ResponseType); ok {
+		result.Data = typed
+	} else {
+		// The extracted value is a generic JSON tree, it is encoded again to be decoded into ResponseType. Numbers
+		// are json.Number so they survive the round trip, DoGraphQLOperation avoids it by decoding in a single pass
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnexpected, err)
+		}
+		if err := json.Unmarshal(b, &result.Data); err != nil {
+			return nil, fmt.Errorf("%w: unexpected result format, %w", ErrUnexpected, err)
+		}
 	}
 
-	var marshalledRes ResponseType
-	err = json.Unmarshal(b, &marshalledRes)
-	if err != nil {
-		return empty, fmt.Errorf("%w: unexpected result format, %w", ErrUnexpected, err)
+	for _, at := range pageInfos {
+		result.PageInfos[at.Path] = at.PageInfo
+		result.PageInfo = at.PageInfo
 	}
-
-	return marshalledRes, nil
+	return result, nil
 }
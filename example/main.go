@@ -1,34 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/samber/lo"
 	"github.com/vpineda1996/wealthgo/client"
 )
 
-// persistSession saves the session to a file
-func persistSession(sessionJSON string) error {
-	return os.WriteFile("session.json", []byte(sessionJSON), 0644)
-}
-
-// loadSession loads the session from a file
-func loadSession() (*client.WSAPISession, error) {
-	data, err := os.ReadFile("session.json")
-	if err != nil {
-		return nil, err
-	}
-
-	var session client.WSAPISession
-	if err := json.Unmarshal(data, &session); err != nil {
-		return nil, err
-	}
-
-	return &session, nil
-}
+// sessionStore keeps the session in a file only the current user can read
+var sessionStore = client.NewFileSessionStore("session.json")
 
 // prettyPrint prints a JSON representation of the data
 func prettyPrint(data interface{}) {
@@ -43,16 +27,15 @@ func prettyPrint(data interface{}) {
 func main() {
 	// Step 1: Try to load an existing session
 	fmt.Println("Attempting to load existing session...")
-	session, err := loadSession()
+	session, err := sessionStore.Load(context.Background())
 	var api *client.WealthsimpleAPI
 
-	if err != nil {
+	if errors.Is(err, client.ErrSessionNotFound) {
 		fmt.Println("No existing session found, logging in...")
 		// Step 2: If no session exists, log in with credentials
 		// Replace with actual credentials
 
 		var username, password string
-		var otpAnswer string
 
 		fmt.Print("Enter username: ")
 		fmt.Scanln(&username)
@@ -60,22 +43,30 @@ func main() {
 		fmt.Print("Enter password: ")
 		fmt.Scanln(&password)
 
-		fmt.Print("Enter OTP (if required): ")
-		fmt.Scanln(&otpAnswer)
-
-		api, err = client.Login(username, password, otpAnswer, persistSession, "")
-		if err != nil {
-			if err == client.ErrOTPRequired {
-				fmt.Println("OTP is required. Please provide OTP and try again.")
-				return
+		api, err = client.Login(username, password, "", nil, "", client.WithSessionStore(sessionStore))
+		var challenge *client.LoginChallenge
+		for errors.As(err, &challenge) && errors.Is(err, client.ErrOTPRequired) {
+			// Answer the OTP without sending the credentials again
+			var otpAnswer string
+			fmt.Printf("Enter OTP %v: ", challenge.Methods)
+			fmt.Scanln(&otpAnswer)
+
+			api, err = challenge.Submit(context.Background(), otpAnswer)
+			if errors.Is(err, client.ErrOTPInvalid) {
+				fmt.Println("Invalid OTP, try again.")
+				err = challenge
 			}
+		}
+		if err != nil {
 			log.Fatalf("Login failed: %v", err)
 		}
 		fmt.Println("Login successful!")
+	} else if err != nil {
+		log.Fatalf("Failed to load session: %v", err)
 	} else {
 		fmt.Println("Session loaded successfully!")
 		// Step 3: Create API instance from existing session
-		api, err = client.FromToken(session, persistSession)
+		api, err = client.FromToken(session, nil, client.WithSessionStore(sessionStore))
 		if err != nil {
 			log.Fatalf("Failed to create API from token: %v", err)
 		}